@@ -145,6 +145,7 @@ func (c *chassis) initialize() error {
 	}
 	bootstrap.Bootstrap()
 	if archaius.GetBool("cse.service.registry.disabled", false) != true {
+		registry.SetLBStrategyChecker(loadbalancer.StrategyInstalled)
 		err := registry.Enable()
 		if err != nil {
 			return err
@@ -249,6 +250,13 @@ func Run() {
 	case err := <-server.ErrRuntime:
 		lager.Logger.Info("got Server Error " + err.Error())
 	}
+	if !config.GetRegistratorDisable() {
+		if err := registry.DrainInstance(0); err != nil {
+			lager.Logger.Warnf("drain instance failed: %s", err)
+		}
+		registry.DefaultInstanceHeartbeat.Stop()
+		registry.DefaultMetadataFileWatcher.Stop()
+	}
 	for name, s := range server.GetServers() {
 		lager.Logger.Info("stopping server " + name + "...")
 		err := s.Stop()
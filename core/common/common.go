@@ -19,10 +19,11 @@ const (
 const (
 	Env = "go-chassis_ENV"
 
-	EnvNodeIP      = "HOSTING_SERVER_IP"
-	EnvSchemaRoot  = "SCHEMA_ROOT"
-	EnvProjectID   = "CSE_PROJECT_ID"
-	EnvCSEEndpoint = "PAAS_CSE_ENDPOINT"
+	EnvNodeIP           = "HOSTING_SERVER_IP"
+	EnvSchemaRoot       = "SCHEMA_ROOT"
+	EnvProjectID        = "CSE_PROJECT_ID"
+	EnvCSEEndpoint      = "PAAS_CSE_ENDPOINT"
+	EnvSkipRegistration = "CHASSIS_SKIP_REGISTRATION"
 )
 
 // constant environment keys service center, config center, monitor server addresses
@@ -67,6 +68,14 @@ const (
 	LBSessionID     = "go-chassisLB"
 )
 
+// constant for built in load balance strategies
+const (
+	StrategyRoundRobin        = "RoundRobin"
+	StrategyRandom            = "Random"
+	StrategySessionStickiness = "SessionStickiness"
+	StrategyLatency           = "WeightedResponse"
+)
+
 // SessionNameSpaceKey metadata session namespace key
 const SessionNameSpaceKey = "_Session_Namespace"
 
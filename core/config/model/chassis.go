@@ -122,8 +122,12 @@ type ReferencesStruct struct {
 type Protocol struct {
 	Listen       string `yaml:"listenAddress"`
 	Advertise    string `yaml:"advertiseAddress"`
+	SSLAdvertise string `yaml:"sslAdvertiseAddress"`
 	WorkerNumber int    `yaml:"workerNumber"`
 	Transport    string `yaml:"transport"`
+	BasePath     string `yaml:"basePath"`
+	HealthPath   string `yaml:"healthPath"`
+	Weight       int    `yaml:"weight"`
 }
 
 // MicroserviceCfg microservice.yaml 配置项
@@ -143,6 +147,112 @@ type MicServiceStruct struct {
 	Properties         map[string]string   `yaml:"properties"`
 	InstanceProperties map[string]string   `yaml:"instance_properties"`
 	ServicePaths       []ServicePathStruct `yaml:"paths"`
+	Instance           InstanceStruct      `yaml:"instance"`
+	DisplayName        string              `yaml:"displayName"`
+	Categories         []string            `yaml:"categories"`
+	MinClientVersion   string              `yaml:"minClientVersion"`
+	RouteRules         []*RouteRule        `yaml:"routeRules"`
+	LBStrategy         string              `yaml:"lbStrategy"`
+	Environments       []string            `yaml:"environments"`
+	Owner              string              `yaml:"owner"`
+	Contact            string              `yaml:"contact"`
+	Tier               string              `yaml:"tier"`
+	DocsURL            string              `yaml:"docsURL"`
+	Deprecated         bool                `yaml:"deprecated"`
+	DeprecationMessage string              `yaml:"deprecationMessage"`
+	SunsetDate         string              `yaml:"sunsetDate"`
+	MaxConcurrency     int                 `yaml:"maxConcurrency"`
+	RateLimitHint      int                 `yaml:"rateLimitHint"`
+	ServiceKey         ServiceKeyStruct    `yaml:"serviceKey"`
+	Dependencies       []DependencyStruct  `yaml:"dependencies"`
+	AuthSchemes        []string            `yaml:"authSchemes"`
+}
+
+// ServiceKeyStruct overrides the components of the key registry identifies the micro-service by,
+// empty components are computed as usual
+type ServiceKeyStruct struct {
+	App         string `yaml:"app"`
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Environment string `yaml:"environment"`
+}
+
+// DependencyStruct declares a provider the micro-service depends on,
+// Version is a version rule like latest, 1.0.0, 1.0.0+ or 1.0.0-2.0.0, AppID defaults to the app of the consumer
+type DependencyStruct struct {
+	Name    string `yaml:"name"`
+	AppID   string `yaml:"appId"`
+	Version string `yaml:"version"`
+}
+
+// InstanceStruct declares hints advertised in instance metadata,
+// TrafficPercent and Priority are pointers since 0 is a valid value
+type InstanceStruct struct {
+	Capacity         int                      `yaml:"capacity"`
+	InitialStatus    string                   `yaml:"initialStatus"`
+	Tags             []string                 `yaml:"tags"`
+	Encodings        []string                 `yaml:"encodings"`
+	Kubernetes       KubernetesMetadataStruct `yaml:"kubernetes"`
+	ShutdownGrace    string                   `yaml:"shutdownGrace"`
+	Warmup           string                   `yaml:"warmup"`
+	TrafficPercent   *int                     `yaml:"trafficPercent"`
+	NodeID           NodeIDStruct             `yaml:"nodeID"`
+	Build            BuildInfoStruct          `yaml:"build"`
+	Priority         *int                     `yaml:"priority"`
+	Limits           LimitsMetadataStruct     `yaml:"limits"`
+	Flags            []string                 `yaml:"flags"`
+	RegionPreference []string                 `yaml:"regionPreference"`
+	Synthetic        SyntheticEndpointsStruct `yaml:"synthetic"`
+	Affinity         map[string]string        `yaml:"affinity"`
+	MetadataFile     MetadataFileStruct       `yaml:"metadataFile"`
+	Cohort           string                   `yaml:"cohort"`
+	Locale           string                   `yaml:"locale"`
+	Timezone         string                   `yaml:"timezone"`
+}
+
+// MetadataFileStruct declares a json or yaml file of flat host facts merged into instance metadata,
+// the file is polled at watchInterval for changes
+type MetadataFileStruct struct {
+	Path          string `yaml:"path"`
+	WatchInterval string `yaml:"watchInterval"`
+}
+
+// SyntheticEndpointsStruct declares test targets overriding protocol endpoints for chaos testing,
+// they are applied only if enabled
+type SyntheticEndpointsStruct struct {
+	Enabled   bool              `yaml:"enabled"`
+	Endpoints map[string]string `yaml:"endpoints"`
+}
+
+// BuildInfoStruct declares the build info advertised in instance metadata, configured values take precedence over ldflags
+type BuildInfoStruct struct {
+	Enabled bool   `yaml:"enabled"`
+	Commit  string `yaml:"commit"`
+	Branch  string `yaml:"branch"`
+	Time    string `yaml:"time"`
+}
+
+// NodeIDStruct declares the stable identifier of the node instance runs on, value takes precedence over env
+type NodeIDStruct struct {
+	Enabled bool   `yaml:"enabled"`
+	Value   string `yaml:"value"`
+	Env     string `yaml:"env"`
+}
+
+// LimitsMetadataStruct declares the env vars cpu and memory limits are read from before cgroup
+type LimitsMetadataStruct struct {
+	Enabled   bool   `yaml:"enabled"`
+	CPUEnv    string `yaml:"cpuEnv"`
+	MemoryEnv string `yaml:"memoryEnv"`
+}
+
+// KubernetesMetadataStruct declares the downward API env vars advertised in instance metadata
+type KubernetesMetadataStruct struct {
+	Enabled      bool   `yaml:"enabled"`
+	PodNameEnv   string `yaml:"podNameEnv"`
+	NamespaceEnv string `yaml:"namespaceEnv"`
+	NodeNameEnv  string `yaml:"nodeNameEnv"`
+	PodIPEnv     string `yaml:"podIPEnv"`
 }
 
 // ServicePathStruct having info about service path and property
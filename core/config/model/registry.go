@@ -32,14 +32,75 @@ type RegistryStruct struct {
 
 //RegistratorStruct service registry config struct
 type RegistratorStruct struct {
-	Disable         bool                     `yaml:"disabled"`
-	Type            string                   `yaml:"type"`
-	Scope           string                   `yaml:"scope"`
-	Address         string                   `yaml:"address"`
-	RefreshInterval string                   `yaml:"refreshInterval"`
-	Tenant          string                   `yaml:"tenant"`
-	AutoRegister    string                   `yaml:"register"`
-	APIVersion      RegistryAPIVersionStruct `yaml:"api"`
+	Disable                 bool                     `yaml:"disabled"`
+	Type                    string                   `yaml:"type"`
+	Scope                   string                   `yaml:"scope"`
+	Address                 string                   `yaml:"address"`
+	RefreshInterval         string                   `yaml:"refreshInterval"`
+	Tenant                  string                   `yaml:"tenant"`
+	AutoRegister            string                   `yaml:"register"`
+	APIVersion              RegistryAPIVersionStruct `yaml:"api"`
+	Timeout                 string                   `yaml:"timeout"`
+	Operations              RegistratorOperations    `yaml:"operations"`
+	VerifyScope             bool                     `yaml:"verifyScope"`
+	FallbackAddress         string                   `yaml:"fallbackAddress"`
+	KeyPrefix               string                   `yaml:"keyPrefix"`
+	PrefixUserKeys          bool                     `yaml:"prefixUserKeys"`
+	ReservedKeys            string                   `yaml:"reservedKeys"`
+	AsyncSchemas            bool                     `yaml:"asyncSchemas"`
+	RetryBudget             string                   `yaml:"retryBudget"`
+	ClearInstanceProperties bool                     `yaml:"clearInstanceProperties"`
+	AppID                   string                   `yaml:"appId"`
+	DeleteStaleSchemas      bool                     `yaml:"deleteStaleSchemas"`
+	DataCenterPlaceholder   string                   `yaml:"dataCenterPlaceholder"`
+	BannerLogLevel          string                   `yaml:"bannerLogLevel"`
+	ServerCheck             string                   `yaml:"serverCheck"`
+	RateLimit               int                      `yaml:"rateLimit"`
+	PublishRouteRules       bool                     `yaml:"publishRouteRules"`
+	Checkpoint              string                   `yaml:"checkpoint"`
+	DetectNodeIP            bool                     `yaml:"detectNodeIP"`
+	NodeIPInterface         string                   `yaml:"nodeIPInterface"`
+	HeartbeatInterval       string                   `yaml:"heartbeatInterval"`
+	HeartbeatInitialDelay   string                   `yaml:"heartbeatInitialDelay"`
+	RegistrationOrder       string                   `yaml:"registrationOrder"`
+	EndpointScheme          bool                     `yaml:"endpointScheme"`
+	RegisterOnNotFound      bool                     `yaml:"registerOnNotFound"`
+	Tracing                 bool                     `yaml:"tracing"`
+	DisableChassisVersion   bool                     `yaml:"disableChassisVersion"`
+	StartTime               bool                     `yaml:"startTime"`
+	Skip                    bool                     `yaml:"skip"`
+	Strict                  bool                     `yaml:"strict"`
+	AvailableZones          []string                 `yaml:"availableZones"`
+	Tiers                   []string                 `yaml:"tiers"`
+	MaxSchemas              int                      `yaml:"maxSchemas"`
+	MaxSchemasPolicy        string                   `yaml:"maxSchemasPolicy"`
+	ValidateSchemas         string                   `yaml:"validateSchemas"`
+	EmptySchemas            string                   `yaml:"emptySchemas"`
+	SchemaConcurrency       int                      `yaml:"schemaConcurrency"`
+	SchemaCompressThreshold int                      `yaml:"schemaCompressThreshold"`
+	RequireSignature        bool                     `yaml:"requireSignature"`
+	CircuitBreaker          RegistratorBreaker       `yaml:"circuitBreaker"`
+	EndpointMap             string                   `yaml:"endpointMap"`
+	MetadataEncoding        string                   `yaml:"metadataEncoding"`
+	UnsupportedProperties   string                   `yaml:"unsupportedProperties"`
+}
+
+//RegistratorBreaker defines the circuit breaker of registrator calls
+type RegistratorBreaker struct {
+	FailureThreshold int    `yaml:"failureThreshold"`
+	Cooldown         string `yaml:"cooldown"`
+}
+
+//RegistratorOperations defines the config of each registrator operation
+type RegistratorOperations struct {
+	RegisterService  OperationStruct `yaml:"registerService"`
+	AddSchemas       OperationStruct `yaml:"addSchemas"`
+	RegisterInstance OperationStruct `yaml:"registerInstance"`
+}
+
+//OperationStruct registrator operation config struct
+type OperationStruct struct {
+	Timeout string `yaml:"timeout"`
 }
 
 //ServiceDiscoveryStruct service discovery config struct
@@ -55,6 +116,14 @@ type ServiceDiscoveryStruct struct {
 	ConfigPath      string                   `yaml:"configPath"`
 	APIVersion      RegistryAPIVersionStruct `yaml:"api"`
 	HealthCheck     bool                     `yaml:"healthCheck"`
+	Preload         PreloadStruct            `yaml:"preload"`
+	DataCenterScope bool                     `yaml:"dataCenterScope"`
+}
+
+//PreloadStruct defines the preload of provider instances after registration
+type PreloadStruct struct {
+	Enabled bool   `yaml:"enabled"`
+	Timeout string `yaml:"timeout"`
 }
 
 //ContractDiscoveryStruct contract discovery config struct
@@ -1,6 +1,9 @@
 package config
 
-import "github.com/go-chassis/go-archaius"
+import (
+	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/core/config/model"
+)
 
 // GetServiceDiscoveryType returns the Type of SD registry
 func GetServiceDiscoveryType() string {
@@ -76,3 +79,13 @@ func GetServiceDiscoveryConfigPath() string {
 	}
 	return DefaultConfigPath
 }
+
+// GetServiceDiscoveryPreload returns the preload config of provider instances
+func GetServiceDiscoveryPreload() model.PreloadStruct {
+	return GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.Preload
+}
+
+// GetServiceDiscoveryDataCenterScope returns whether service id lookup is scoped by the data center of self instance
+func GetServiceDiscoveryDataCenterScope() bool {
+	return GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.DataCenterScope
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"os"
+	"strconv"
+
 	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config/model"
 )
 
 // GetRegistratorType returns the Type of service registry
@@ -60,3 +64,231 @@ func GetRegistratorDisable() bool {
 	}
 	return archaius.GetBool("cse.service.registry.disabled", false)
 }
+
+// GetRegistratorTimeout returns the overall timeout of registrator operations
+func GetRegistratorTimeout() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.Timeout
+}
+
+// GetRegistratorOperations returns the per operation config of registrator
+func GetRegistratorOperations() model.RegistratorOperations {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.Operations
+}
+
+// GetRegistratorVerifyScope returns whether to verify the registered scope
+func GetRegistratorVerifyScope() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope
+}
+
+// GetRegistratorFallbackAddress returns the host advertised when advertise host can not be resolved
+func GetRegistratorFallbackAddress() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress
+}
+
+// GetRegistratorKeyPrefix returns the prefix of metadata keys written by chassis
+func GetRegistratorKeyPrefix() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.KeyPrefix
+}
+
+// GetRegistratorPrefixUserKeys returns whether user supplied metadata keys are prefixed too
+func GetRegistratorPrefixUserKeys() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.PrefixUserKeys
+}
+
+// GetRegistratorReservedKeys returns the policy of user metadata using reserved keys
+func GetRegistratorReservedKeys() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.ReservedKeys
+}
+
+// GetRegistratorAsyncSchemas returns whether schemas are uploaded in background
+func GetRegistratorAsyncSchemas() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.AsyncSchemas
+}
+
+// GetRegistratorRetryBudget returns max total retry time shared by registration operations
+func GetRegistratorRetryBudget() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget
+}
+
+// GetRegistratorClearInstanceProperties returns whether to clear instance properties left in registry when none is configured,
+// chassis managed metadata is kept
+func GetRegistratorClearInstanceProperties() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.ClearInstanceProperties
+}
+
+// GetRegistratorAppID returns the app id which overrides runtime app in registration
+func GetRegistratorAppID() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.AppID
+}
+
+// GetRegistratorDeleteStaleSchemas returns whether to delete schemas no longer defined locally on re-registration
+func GetRegistratorDeleteStaleSchemas() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.DeleteStaleSchemas
+}
+
+// GetRegistratorDataCenterPlaceholder returns the data center name used when only available zone is configured
+func GetRegistratorDataCenterPlaceholder() string {
+	if GlobalDefinition.Cse.Service.Registry.Registrator.DataCenterPlaceholder != "" {
+		return GlobalDefinition.Cse.Service.Registry.Registrator.DataCenterPlaceholder
+	}
+	return common.DefaultValue
+}
+
+// GetRegistratorBannerLogLevel returns the log level of registration banner
+func GetRegistratorBannerLogLevel() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.BannerLogLevel
+}
+
+// GetRegistratorServerCheck returns how to handle protocols advertised without running server
+func GetRegistratorServerCheck() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.ServerCheck
+}
+
+// GetRegistratorRateLimit returns the max registrator operations per second, 0 means no limit
+func GetRegistratorRateLimit() int {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.RateLimit
+}
+
+// GetRegistratorPublishRouteRules returns whether to publish route rules of self micro-service to registry
+func GetRegistratorPublishRouteRules() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.PublishRouteRules
+}
+
+// GetRegistratorCheckpoint returns the path of registration checkpoint file, empty means no checkpoint
+func GetRegistratorCheckpoint() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.Checkpoint
+}
+
+// GetRegistratorDetectNodeIP returns whether to detect node ip when it is not set
+func GetRegistratorDetectNodeIP() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.DetectNodeIP
+}
+
+// GetRegistratorNodeIPInterface returns the network interface node ip is preferred to be detected from
+func GetRegistratorNodeIPInterface() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.NodeIPInterface
+}
+
+// GetRegistratorHeartbeatInterval returns the interval of refreshing the ttl of self instance
+func GetRegistratorHeartbeatInterval() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval
+}
+
+// GetRegistratorHeartbeatInitialDelay returns how long the first heartbeat of self instance waits for
+func GetRegistratorHeartbeatInitialDelay() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInitialDelay
+}
+
+// GetRegistratorRegistrationOrder returns whether self micro-service or instance is registered first
+func GetRegistratorRegistrationOrder() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder
+}
+
+// GetRegistratorEndpointScheme returns whether advertised endpoints are annotated with scheme in their query
+func GetRegistratorEndpointScheme() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.EndpointScheme
+}
+
+// GetRegistratorRegisterOnNotFound returns whether instance registration registers self micro-service if it is not found
+func GetRegistratorRegisterOnNotFound() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.RegisterOnNotFound
+}
+
+// GetRegistratorTracing returns whether registration is traced with the global tracer
+func GetRegistratorTracing() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.Tracing
+}
+
+// GetRegistratorChassisVersion returns whether chassis version is written to instance metadata, it is enabled by default
+func GetRegistratorChassisVersion() bool {
+	return !GlobalDefinition.Cse.Service.Registry.Registrator.DisableChassisVersion
+}
+
+// GetRegistratorStartTime returns whether start time is written to instance metadata, it is disabled by default
+func GetRegistratorStartTime() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.StartTime
+}
+
+// GetRegistratorMetadataEncoding returns the encoding of metadata values sent to registry, empty means plain
+func GetRegistratorMetadataEncoding() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding
+}
+
+// GetRegistratorUnsupportedProperties returns how instance properties are handled if registrator can not update them, empty means skip
+func GetRegistratorUnsupportedProperties() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.UnsupportedProperties
+}
+
+// GetRegistratorSkip returns whether registration skips registry interaction,
+// it is enabled by skip or by env CHASSIS_SKIP_REGISTRATION=true
+func GetRegistratorSkip() bool {
+	if GlobalDefinition.Cse.Service.Registry.Registrator.Skip {
+		return true
+	}
+	skip, _ := strconv.ParseBool(os.Getenv(common.EnvSkipRegistration))
+	return skip
+}
+
+// GetRegistratorStrict returns whether registration requires the optional declarations such as owner and contact,
+// and rejects the config problems which are only warned otherwise
+func GetRegistratorStrict() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.Strict
+}
+
+// GetRegistratorAvailableZones returns the known zones region.availableZone must be one of, empty means no validation
+func GetRegistratorAvailableZones() []string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.AvailableZones
+}
+
+// GetRegistratorTiers returns the service tiers service_description.tier must be one of, default is gold, silver and bronze
+func GetRegistratorTiers() []string {
+	if tiers := GlobalDefinition.Cse.Service.Registry.Registrator.Tiers; len(tiers) != 0 {
+		return tiers
+	}
+	return []string{"gold", "silver", "bronze"}
+}
+
+// GetRegistratorMaxSchemas returns the max number of schemas uploaded, 0 means unlimited
+func GetRegistratorMaxSchemas() int {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.MaxSchemas
+}
+
+// GetRegistratorValidateSchemas returns how registration handles schema contents which can not be parsed, empty means no validation
+func GetRegistratorValidateSchemas() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.ValidateSchemas
+}
+
+// GetRegistratorSchemaConcurrency returns the max number of schemas uploaded at the same time, 0 or 1 means one by one
+func GetRegistratorSchemaConcurrency() int {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.SchemaConcurrency
+}
+
+// GetRegistratorSchemaCompressThreshold returns the size in bytes above which schema content is compressed, 0 means never
+func GetRegistratorSchemaCompressThreshold() int {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.SchemaCompressThreshold
+}
+
+// GetRegistratorEmptySchemas returns how registration handles schemas with empty content, empty means skip
+func GetRegistratorEmptySchemas() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.EmptySchemas
+}
+
+// GetRegistratorMaxSchemasPolicy returns how registration handles schemas exceeding maxSchemas
+func GetRegistratorMaxSchemasPolicy() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.MaxSchemasPolicy
+}
+
+// GetRegistratorRequireSignature returns whether registry requires signed registration payloads
+func GetRegistratorRequireSignature() bool {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.RequireSignature
+}
+
+// GetRegistratorCircuitBreaker returns the circuit breaker config of registrator calls
+func GetRegistratorCircuitBreaker() model.RegistratorBreaker {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.CircuitBreaker
+}
+
+// GetRegistratorEndpointMap returns how broken protocol endpoints are handled when building the endpoint map
+func GetRegistratorEndpointMap() string {
+	return GlobalDefinition.Cse.Service.Registry.Registrator.EndpointMap
+}
@@ -3,6 +3,7 @@ package endpoint
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/go-chassis/go-chassis/core/lager"
@@ -32,10 +33,11 @@ func GetEndpointFromServiceCenter(appID, microService, version string) (string,
 	}
 
 	for _, instance := range instances {
-		for _, value := range instance.EndpointsMap {
+		for name := range instance.EndpointsMap {
+			value := instance.Endpoint(name)
 			if strings.Contains(value, "?") {
-				separation := strings.Split(value, "?")
-				if separation[1] == "sslEnabled=true" {
+				separation := strings.SplitN(value, "?", 2)
+				if query, err := url.ParseQuery(separation[1]); err == nil && query.Get("sslEnabled") == "true" {
 					endPoint = "https://" + separation[0]
 				} else {
 					endPoint = "http://" + separation[0]
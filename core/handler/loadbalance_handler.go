@@ -10,6 +10,7 @@ import (
 	"github.com/go-chassis/go-chassis/core/invocation"
 	"github.com/go-chassis/go-chassis/core/lager"
 	"github.com/go-chassis/go-chassis/core/loadbalancer"
+	"github.com/go-chassis/go-chassis/core/registry"
 
 	backoffUtil "github.com/go-chassis/go-chassis/pkg/backoff"
 
@@ -77,7 +78,7 @@ func (lb *LBHandler) getEndpoint(i *invocation.Invocation, lbConfig control.Load
 		lager.Logger.Errorf(lbErr.Error())
 		return "", lbErr
 	}
-	return ep, nil
+	return registry.EndpointAddress(ep), nil
 }
 
 // Handle to handle the load balancing
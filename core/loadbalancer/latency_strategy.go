@@ -145,7 +145,7 @@ func (r *WeightedResponseStrategy) Pick() (*registry.MicroServiceInstance, error
 		}
 		LatencyMapRWMutex.RUnlock()
 		for _, instance := range r.instances {
-			if instanceAddr == instance.EndpointsMap[r.protocol] {
+			if instanceAddr == registry.EndpointAddress(instance.EndpointsMap[r.protocol]) {
 				return instance, nil
 			}
 		}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/go-chassis/go-archaius"
+	"github.com/go-chassis/go-chassis/core/common"
 	"github.com/go-chassis/go-chassis/core/config"
 	"github.com/go-chassis/go-chassis/core/lager"
 	"github.com/go-chassis/go-chassis/core/registry"
@@ -14,10 +15,10 @@ import (
 
 // constant strings for load balance variables
 const (
-	StrategyRoundRobin        = "RoundRobin"
-	StrategyRandom            = "Random"
-	StrategySessionStickiness = "SessionStickiness"
-	StrategyLatency           = "WeightedResponse"
+	StrategyRoundRobin        = common.StrategyRoundRobin
+	StrategyRandom            = common.StrategyRandom
+	StrategySessionStickiness = common.StrategySessionStickiness
+	StrategyLatency           = common.StrategyLatency
 	OperatorEqual             = "="
 	OperatorGreater           = ">"
 	OperatorSmaller           = "<"
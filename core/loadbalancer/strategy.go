@@ -17,6 +17,12 @@ func init() {
 	i = rand.Int()
 }
 
+// StrategyInstalled tells whether name is an installed strategy, chassis sets it as the load balance strategy checker of registry
+func StrategyInstalled(name string) bool {
+	_, err := GetStrategyPlugin(name)
+	return err == nil
+}
+
 // InstallStrategy install strategy
 func InstallStrategy(name string, s func() Strategy) {
 	strategies[name] = s
@@ -6,8 +6,10 @@ import (
 	"testing"
 
 	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
 	"github.com/go-chassis/go-chassis/core/loadbalancer"
 	"github.com/go-chassis/go-chassis/core/registry"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,3 +60,22 @@ func TestRoundRobinStrategy_Pick(t *testing.T) {
 	}
 
 }
+
+func TestLBStrategyChecked(t *testing.T) {
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.MicroserviceDefinition = &model.MicroserviceCfg{
+		ServiceDescription: model.MicServiceStruct{Name: "TestService", Version: "0.0.1", LBStrategy: "Fastest"},
+	}
+	runtime.App = "default"
+	registry.SetLBStrategyChecker(loadbalancer.StrategyInstalled)
+	defer registry.SetLBStrategyChecker(nil)
+	err := registry.ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.lbStrategy")
+
+	loadbalancer.InstallStrategy("Fastest", func() loadbalancer.Strategy { return &loadbalancer.RandomStrategy{} })
+	config.MicroserviceDefinition.ServiceDescription.LBStrategy = loadbalancer.StrategyRoundRobin
+	assert.NoError(t, registry.ValidateRegistrationConfig())
+	config.MicroserviceDefinition.ServiceDescription.LBStrategy = "Fastest"
+	assert.NoError(t, registry.ValidateRegistrationConfig(), "installed strategies are accepted")
+}
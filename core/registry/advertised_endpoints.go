@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// selfEndpoints is the endpoint map of self instance in registry,
+// instancesEndpoints are the endpoint maps of every self instance registered by this process, keyed by instance id
+var selfEndpoints = make(map[string]string)
+var instancesEndpoints = make(map[string]map[string]string)
+var selfEndpointsMu sync.RWMutex
+
+// EndpointsUpdater is implemented by registrators which are able to update endpoints of an instance in place
+type EndpointsUpdater interface {
+	UpdateMicroServiceInstanceEndpoints(microServiceID, microServiceInstanceID string, endpoints map[string]string) error
+}
+
+func setSelfEndpoints(iid string, eps map[string]string) {
+	selfEndpointsMu.Lock()
+	selfEndpoints = copyMetadata(eps)
+	instancesEndpoints[iid] = copyMetadata(eps)
+	selfEndpointsMu.Unlock()
+}
+
+// removeAdvertisedEndpoints forgets the endpoints of self instance iid once it is no longer registered
+func removeAdvertisedEndpoints(iid string) {
+	selfEndpointsMu.Lock()
+	delete(instancesEndpoints, iid)
+	selfEndpointsMu.Unlock()
+}
+
+// GetAdvertisedEndpoints returns a copy of the endpoint map of self instance in registry
+func GetAdvertisedEndpoints() map[string]string {
+	selfEndpointsMu.RLock()
+	defer selfEndpointsMu.RUnlock()
+	return copyMetadata(selfEndpoints)
+}
+
+// GetAllAdvertisedEndpoints returns copies of the endpoint maps of every self instance registered by this process, keyed by instance id
+func GetAllAdvertisedEndpoints() map[string]map[string]string {
+	selfEndpointsMu.RLock()
+	defer selfEndpointsMu.RUnlock()
+	all := make(map[string]map[string]string, len(instancesEndpoints))
+	for iid, eps := range instancesEndpoints {
+		all[iid] = copyMetadata(eps)
+	}
+	return all
+}
+
+// instanceEndpoints returns a copy of InstanceEndpoints, nil if they are not set
+func instanceEndpoints() map[string]string {
+	selfEndpointsMu.RLock()
+	defer selfEndpointsMu.RUnlock()
+	if InstanceEndpoints == nil {
+		return nil
+	}
+	return copyMetadata(InstanceEndpoints)
+}
+
+// UpdateAdvertisedEndpoints validates eps and pushes them to registry as the endpoints of self instance,
+// if registrator does not implement EndpointsUpdater, self instance is registered again with its instance id so that it is updated in place,
+// eps are kept as InstanceEndpoints so that later re-registration advertises them too
+func UpdateAdvertisedEndpoints(eps map[string]string) error {
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	if len(eps) == 0 {
+		return errors.New("advertised endpoints must not be empty")
+	}
+	for _, name := range sortedKeys(eps) {
+		if err := validEndpoint(eps[name]); err != nil {
+			return err
+		}
+	}
+	published, md, err := publishedEndpoints(eps)
+	if err != nil {
+		return err
+	}
+	if registrationSkipped() {
+		lager.Logger.Debugf("Registration is disabled, endpoints of %s/%s are only updated locally", sid, iid)
+		err = updateSelfMetadata(withEndpointsMetadata(md), func(map[string]string) error { return nil })
+	} else if updater, ok := asEndpointsUpdater(DefaultRegistrator); ok {
+		err = updateEndpoints(updater, sid, iid, published, md)
+	} else {
+		err = updateEndpointsInPlace(sid, iid, published, md)
+	}
+	if err != nil {
+		lager.Logger.Errorf("Update advertised endpoints failed, microServiceID/instanceID = %s/%s: %s", sid, iid, err)
+		return err
+	}
+	selfEndpointsMu.Lock()
+	InstanceEndpoints = copyMetadata(eps)
+	selfEndpointsMu.Unlock()
+	setSelfEndpoints(iid, published)
+	lager.Logger.Infof("Update advertised endpoints success, endpoints %v", published)
+	return nil
+}
+
+// withEndpointsMetadata returns a change of self metadata replacing the chassis managed metadata derived from endpoints with epsMD,
+// synthetic mark is dropped unless epsMD has it
+func withEndpointsMetadata(epsMD map[string]string) func(md, properties map[string]string) (map[string]string, map[string]string) {
+	return func(md, properties map[string]string) (map[string]string, map[string]string) {
+		delete(md, chassisKey(MDSynthetic))
+		for k, v := range epsMD {
+			md[k] = v
+		}
+		return md, properties
+	}
+}
+
+// updateEndpoints pushes eps with updater, then pushes self metadata along with epsMD derived from eps
+func updateEndpoints(updater EndpointsUpdater, sid, iid string, eps, epsMD map[string]string) error {
+	if err := updater.UpdateMicroServiceInstanceEndpoints(sid, iid, eps); err != nil {
+		return err
+	}
+	return updateSelfMetadata(withEndpointsMetadata(epsMD), func(md map[string]string) error {
+		encoded, err := registeredInstanceMetadata("", eps, md)
+		if err != nil {
+			return err
+		}
+		return updateInstanceProperties(DefaultRegistrator, sid, iid, encoded)
+	})
+}
+
+// updateEndpointsInPlace registers self instance again with its instance id, status and metadata along with epsMD derived from eps,
+// metadata is signed again and encoded as it is at registration, it fails if registry assigns another instance id
+func updateEndpointsInPlace(sid, iid string, eps, epsMD map[string]string) error {
+	return updateSelfMetadata(withEndpointsMetadata(epsMD), func(md map[string]string) error {
+		ins := selfInstance(iid, eps, md)
+		if _, err := sealInstance(ins); err != nil {
+			return err
+		}
+		instanceID, err := callForID(OpRegisterInstance, func() (string, error) {
+			return DefaultRegistrator.RegisterServiceInstance(sid, ins)
+		})
+		if err != nil {
+			return err
+		}
+		if instanceID != iid {
+			return fmt.Errorf("registry registered a new instance %s instead of updating %s", instanceID, iid)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// endpointsRegistrator updates endpoints in place
+type endpointsRegistrator struct {
+	*fakeRegistrator
+	endpoints map[string]string
+}
+
+func (e *endpointsRegistrator) UpdateMicroServiceInstanceEndpoints(sid, iid string, eps map[string]string) error {
+	e.endpoints = eps
+	return e.err
+}
+
+func TestUpdateAdvertisedEndpoints(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	eps := map[string]string{"rest": "10.0.0.9:8080"}
+	assert.Equal(t, ErrInstanceNotRegistered, UpdateAdvertisedEndpoints(eps))
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "127.0.0.1:8080", GetAdvertisedEndpoints()["rest"])
+
+	assert.Error(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9"}))
+	assert.Error(t, UpdateAdvertisedEndpoints(nil))
+	assert.Equal(t, 1, len(r.instances))
+
+	assert.NoError(t, UpdateAdvertisedEndpoints(eps))
+	assert.Equal(t, 2, len(r.instances))
+	assert.Equal(t, r.iid, r.instances[1].InstanceID, "registered again with its instance id")
+	assert.Equal(t, eps, r.instances[1].EndpointsMap)
+	assert.Equal(t, eps, GetAdvertisedEndpoints())
+	assert.Equal(t, eps, InstanceEndpoints, "kept for re-registration")
+
+	r.iid = "another"
+	assert.Error(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.10:8080"}))
+	assert.Equal(t, eps, GetAdvertisedEndpoints())
+}
+
+func TestUpdateAdvertisedEndpointsInPlaceMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = MetadataEncodingBase64
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"note": "a b"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	assert.NoError(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9:8080"}))
+	ins := r.instances[1]
+	assert.NotEmpty(t, ins.Metadata[MDSignature], "signed again")
+	assert.NotEqual(t, r.instances[0].Metadata[MDSignature], ins.Metadata[MDSignature])
+	md, err := DecodeMetadata(ins.Metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", md["note"], "encoded as at registration")
+	assert.Equal(t, GetSelfMetadata()["note"], "a b")
+}
+
+func TestUpdateAdvertisedEndpointsPublished(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.EndpointScheme = true
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "false", GetSelfMetadata()[chassisKey(MDSecure)])
+
+	assert.NoError(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9:8443?sslEnabled=true"}))
+	published := map[string]string{"rest": "10.0.0.9:8443?scheme=https&sslEnabled=true"}
+	assert.Equal(t, published, r.instances[1].EndpointsMap, "advertised as at registration")
+	assert.Equal(t, published, GetAdvertisedEndpoints())
+	assert.Equal(t, "true", r.instances[1].Metadata[chassisKey(MDSecure)])
+	assert.Equal(t, "true", GetSelfMetadata()[chassisKey(MDSecure)])
+}
+
+func TestUpdateAdvertisedEndpointsWithUpdater(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	er := &endpointsRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = er
+
+	eps := map[string]string{"rest": "10.0.0.9:8080", "highway": "10.0.0.9:9090"}
+	assert.NoError(t, UpdateAdvertisedEndpoints(eps))
+	assert.Equal(t, eps, er.endpoints)
+	assert.Equal(t, 1, len(r.instances), "instance is not registered again")
+	assert.Equal(t, eps, GetAdvertisedEndpoints())
+	assert.Equal(t, "false", GetSelfMetadata()[chassisKey(MDSecure)])
+
+	assert.NoError(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9:8443?sslEnabled=true"}))
+	assert.Equal(t, "true", GetSelfMetadata()[chassisKey(MDSecure)], "self metadata is refreshed along with endpoints")
+	assert.Equal(t, "true", r.properties[chassisKey(MDSecure)])
+	assert.Equal(t, GetSelfMetadata(), r.properties)
+}
+
+func TestGetAllAdvertisedEndpoints(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	instancesEndpoints = make(map[string]map[string]string)
+	assert.Empty(t, GetAllAdvertisedEndpoints())
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	r.iid = "iid2"
+	InstanceEndpoints = map[string]string{"rest": "10.0.0.9:8080"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	all := GetAllAdvertisedEndpoints()
+	assert.Equal(t, map[string]map[string]string{
+		"iid":  {"rest": "127.0.0.1:8080"},
+		"iid2": {"rest": "10.0.0.9:8080"},
+	}, all)
+	all["iid"]["rest"] = "changed"
+	assert.Equal(t, "127.0.0.1:8080", GetAllAdvertisedEndpoints()["iid"]["rest"], "copies are returned")
+
+	assert.NoError(t, ForceExpireSelfInstance())
+	assert.NotContains(t, GetAllAdvertisedEndpoints(), "iid2")
+	assert.Contains(t, GetAllAdvertisedEndpoints(), "iid")
+}
@@ -0,0 +1,29 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// affinityKey returns the metadata key of affinity hint k, like affinity.zone
+func affinityKey(k string) string {
+	return MDAffinity + "." + k
+}
+
+// affinityMetadata returns the affinity hints keyed by their metadata keys, keys and values are trimmed,
+// they must not be empty
+func affinityMetadata(affinity map[string]string) (map[string]string, error) {
+	md := make(map[string]string, len(affinity))
+	for k, v := range affinity {
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if k == "" {
+			return nil, errors.New("affinity key must not be empty")
+		}
+		if v == "" {
+			return nil, fmt.Errorf("value of affinity [%s] must not be empty", k)
+		}
+		md[affinityKey(k)] = v
+	}
+	return md, nil
+}
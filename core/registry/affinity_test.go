@@ -0,0 +1,26 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAffinityMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	ins := &config.MicroserviceDefinition.ServiceDescription.Instance
+	ins.Affinity = map[string]string{"colocate": " cache ", "spread": "rack"}
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"affinity.colocate": "db"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "cache", r.instances[0].Metadata["affinity.colocate"])
+	assert.Equal(t, "rack", r.instances[0].Metadata["affinity.spread"])
+	assert.Equal(t, "cache", GetSelfMetadata()["affinity.colocate"], "user metadata must not override affinity hints")
+
+	for _, affinity := range []map[string]string{{" ": "cache"}, {"spread": " "}} {
+		ins.Affinity = affinity
+		assert.Error(t, RegisterMicroserviceInstances(), "%v", affinity)
+	}
+	assert.Equal(t, 1, len(r.instances))
+}
@@ -0,0 +1,396 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/config/schema"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/core/metadata"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// prepareMicroService validates the registration config and assembles self micro-service as it is sent to registry
+func prepareMicroService() (*MicroService, error) {
+	service := config.MicroserviceDefinition
+	if e := service.ServiceDescription.Environment; e != "" {
+		lager.Logger.Infof("Microservice environment: [%s]", e)
+	} else {
+		lager.Logger.Debug("No microservice environment defined")
+	}
+	microservice, err := sealedMicroService()
+	if err != nil {
+		return nil, err
+	}
+	microServiceDependencies = declaredDependencies(microservice)
+	lager.Logger.Debugf("Update micro service properties%v", service.ServiceDescription.Properties)
+	logBanner("Framework registered is [ %s:%s ]", microservice.Framework.Name, microservice.Framework.Version)
+	logBanner("Micro service registered by [ %s ]", microservice.RegisterBy)
+	return microservice, nil
+}
+
+// sealedMicroService validates the registration config, then assembles, limits, checks, signs and encodes self micro-service,
+// it is exactly what is sent to registry
+func sealedMicroService() (*MicroService, error) {
+	if err := ValidateRegistrationConfig(); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	microservice := assembleMicroService()
+	var err error
+	if microservice.Schemas, err = limitSchemas(microservice.Schemas); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	if err = checkEmptySchemas(microservice.Schemas); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	if err = validateSchemas(microservice.Schemas); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	if err = signMicroService(microservice); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	if microservice.Metadata, err = encodeMetadata(microservice.Metadata); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, err
+	}
+	return microservice, nil
+}
+
+// joinCategories trims service categories and joins them with comma
+func joinCategories(categories []string) string {
+	trimmed := make([]string, 0, len(categories))
+	for _, c := range categories {
+		trimmed = append(trimmed, strings.TrimSpace(c))
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// joinAuthSchemes lower cases auth schemes and joins them with comma
+func joinAuthSchemes(schemes []string) string {
+	normalized := make([]string, 0, len(schemes))
+	for _, s := range schemes {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(s)))
+	}
+	return strings.Join(normalized, ",")
+}
+
+// serviceEnvironments returns the environment of self micro-service followed by the additional ones, without duplicates
+func serviceEnvironments(env string, envs []string) []string {
+	all := make([]string, 0, len(envs)+1)
+	seen := make(map[string]bool, len(envs)+1)
+	for _, e := range append([]string{env}, envs...) {
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		all = append(all, e)
+	}
+	return all
+}
+
+// registrationApp returns the app self micro-service is registered under,
+// the appId of registrator takes precedence over runtime.App
+func registrationApp() string {
+	if app := config.GetRegistratorAppID(); app != "" {
+		return app
+	}
+	return runtime.App
+}
+
+// normalizePath trims a service path and makes sure it begins with slash
+func normalizePath(p string) string {
+	p = strings.TrimSpace(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
+
+// servicePaths normalizes service paths and merges the properties of duplicated ones in declared order,
+// a later property overrides the earlier one of the same key, empty paths are rejected by ValidateRegistrationConfig
+func servicePaths(svcPaths []model.ServicePathStruct) []ServicePath {
+	var paths []ServicePath
+	index := make(map[string]int, len(svcPaths))
+	for _, svcPath := range svcPaths {
+		p := normalizePath(svcPath.Path)
+		i, ok := index[p]
+		if !ok {
+			index[p] = len(paths)
+			paths = append(paths, ServicePath{Path: p, Property: svcPath.Property})
+			continue
+		}
+		lager.Logger.Warnf("Service path [%s] is duplicated, merge its properties", p)
+		// copied before merging so that config is not modified
+		paths[i].Property = copyMetadata(paths[i].Property)
+		for k, v := range svcPath.Property {
+			paths[i].Property[k] = v
+		}
+	}
+	return paths
+}
+
+// DefaultFrameworkVersion is the framework version registered when framework metadata has no version
+const DefaultFrameworkVersion = "unknown"
+
+// registeredFramework returns a copy of framework metadata with empty fields defaulted,
+// they can be empty in stripped builds
+func registeredFramework(f *metadata.Framework) metadata.Framework {
+	var registered metadata.Framework
+	if f != nil {
+		registered = *f
+	}
+	if registered.Name == "" {
+		lager.Logger.Warnf("Framework name is empty, register it as [%s]", metadata.SdkName)
+		registered.Name = metadata.SdkName
+	}
+	if registered.Version == "" {
+		lager.Logger.Warnf("Framework version is empty, register it as [%s]", DefaultFrameworkVersion)
+		registered.Version = DefaultFrameworkVersion
+	}
+	if registered.Register == "" {
+		lager.Logger.Warnf("Framework register is empty, register it as [%s]", metadata.SdkRegistrationComponent)
+		registered.Register = metadata.SdkRegistrationComponent
+	}
+	return registered
+}
+
+// assembleMicroService builds self micro-service from config as it is sent to registry
+func assembleMicroService() *MicroService {
+	service := config.MicroserviceDefinition
+	schemas, err := schema.GetSchemaIDs(service.ServiceDescription.Name)
+	if err != nil {
+		lager.Logger.Warnf("No schemas file for microservice [%s].", service.ServiceDescription.Name)
+		schemas = make([]string, 0)
+	}
+	if service.ServiceDescription.Level == "" {
+		service.ServiceDescription.Level = common.DefaultLevel
+	}
+	if service.ServiceDescription.Properties == nil {
+		service.ServiceDescription.Properties = make(map[string]string)
+	}
+	framework := registeredFramework(metadata.NewFramework())
+
+	regpaths := servicePaths(service.ServiceDescription.ServicePaths)
+	key := registrationKey()
+	microservice := &MicroService{
+		ServiceID:   runtime.ServiceID,
+		AppID:       key.App,
+		ServiceName: key.Name,
+		Version:     key.Version,
+		Paths:       regpaths,
+		Environment: key.Env,
+		Status:      common.DefaultStatus,
+		Level:       service.ServiceDescription.Level,
+		Schemas:     schemas,
+		Framework: &Framework{
+			Version: framework.Version,
+			Name:    framework.Name,
+		},
+		RegisterBy: framework.Register,
+		Metadata:   make(map[string]string),
+		// TODO allows to customize microservice alias
+		Alias: "",
+	}
+	//update metadata
+	if len(microservice.Alias) == 0 {
+		// if the microservice is allowed to be called by consumers with different appId,
+		// this means that the governance configuration of the consumer side needs to
+		// support key format with appid, like 'cse.loadbalance.{alias}.strategy.name'.
+		microservice.Alias = microservice.AppID + ":" + microservice.ServiceName
+	}
+	if service.ServiceDescription.DisplayName != "" {
+		// only for display, service key is still made of name, version and app
+		microservice.Metadata[chassisKey(MDDisplayName)] = service.ServiceDescription.DisplayName
+	}
+	if len(service.ServiceDescription.Categories) != 0 {
+		// only for catalog filtering, discovery does not use it
+		microservice.Metadata[chassisKey(MDCategories)] = joinCategories(service.ServiceDescription.Categories)
+	}
+	if len(service.ServiceDescription.AuthSchemes) != 0 {
+		// gateways choose how to authenticate calls to the service with it
+		microservice.Metadata[chassisKey(MDAuthSchemes)] = joinAuthSchemes(service.ServiceDescription.AuthSchemes)
+	}
+	if v := service.ServiceDescription.MinClientVersion; v != "" {
+		// consumers below it may warn or refuse to call
+		microservice.Metadata[chassisKey(MDMinClientVersion)] = v
+	}
+	if s := service.ServiceDescription.LBStrategy; s != "" {
+		// default strategy consumers may adopt for this service
+		microservice.Metadata[chassisKey(MDLBStrategy)] = s
+	}
+	if len(service.ServiceDescription.Environments) != 0 {
+		// registry which understands it makes the service discoverable in each environment
+		microservice.Metadata[chassisKey(MDEnvironments)] = strings.Join(
+			serviceEnvironments(service.ServiceDescription.Environment, service.ServiceDescription.Environments), ",")
+	}
+	if owner := strings.TrimSpace(service.ServiceDescription.Owner); owner != "" {
+		// for incident routing by catalog and on-call tooling
+		microservice.Metadata[chassisKey(MDOwner)] = owner
+	}
+	if contact := strings.TrimSpace(service.ServiceDescription.Contact); contact != "" {
+		microservice.Metadata[chassisKey(MDContact)] = contact
+	}
+	if tier := service.ServiceDescription.Tier; tier != "" {
+		// for prioritized routing and capacity planning
+		microservice.Metadata[chassisKey(MDTier)] = tier
+	}
+	if u := service.ServiceDescription.DocsURL; u != "" {
+		// only for developer portals, discovery does not use it
+		microservice.Metadata[chassisKey(MDDocsURL)] = u
+	}
+	if n := service.ServiceDescription.MaxConcurrency; n > 0 {
+		// routers cap in-flight requests per instance with it
+		microservice.Metadata[chassisKey(MDMaxConcurrency)] = strconv.Itoa(n)
+	}
+	if n := service.ServiceDescription.RateLimitHint; n > 0 {
+		// consumers self-throttle to the requests per second providers accept
+		microservice.Metadata[chassisKey(MDRateLimitHint)] = strconv.Itoa(n)
+	}
+	if service.ServiceDescription.Deprecated {
+		// consumers warn when they call a deprecated service
+		microservice.Metadata[chassisKey(MDDeprecated)] = common.TRUE
+		if msg := strings.TrimSpace(service.ServiceDescription.DeprecationMessage); msg != "" {
+			microservice.Metadata[chassisKey(MDDeprecationMsg)] = msg
+		}
+		if date := service.ServiceDescription.SunsetDate; date != "" {
+			microservice.Metadata[chassisKey(MDSunsetDate)] = date
+		}
+	}
+	if config.GetRegistratorScope() == common.ScopeFull {
+		microservice.Metadata[chassisKey(MDAllowCrossApp)] = common.TRUE
+		service.ServiceDescription.Properties[MDAllowCrossApp] = common.TRUE
+	} else {
+		service.ServiceDescription.Properties[MDAllowCrossApp] = common.FALSE
+	}
+	return microservice
+}
+
+// prepareInstance assembles self instance, checks the servers of its endpoints and seals it as it is sent to registry,
+// it returns the plain metadata without signature, and the instance properties in it
+func prepareInstance() (*MicroServiceInstance, map[string]string, map[string]string, error) {
+	ins, properties, err := assembleInstance()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := checkServers(ins.EndpointsMap); err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, nil, nil, err
+	}
+	md, err := sealInstance(ins)
+	if err != nil {
+		lager.Logger.Error(err.Error())
+		return nil, nil, nil, err
+	}
+	return ins, md, properties, nil
+}
+
+// sealInstance signs self instance and encodes its metadata as it is sent to registry,
+// it returns the plain metadata without signature, which is recorded as self metadata once registered
+func sealInstance(ins *MicroServiceInstance) (map[string]string, error) {
+	if err := signInstance(ins); err != nil {
+		return nil, err
+	}
+	md := copyMetadata(ins.Metadata)
+	delete(md, chassisKey(MDSignature))
+	var err error
+	if ins.Metadata, err = encodeMetadata(ins.Metadata); err != nil {
+		return nil, err
+	}
+	return md, nil
+}
+
+// publishedEndpoints runs eps through the endpoint pipeline of registration, transformers, synthetic endpoints and schemes,
+// it returns the endpoints as they are advertised, and the chassis managed metadata derived from them
+func publishedEndpoints(eps map[string]string) (map[string]string, map[string]string, error) {
+	eps, err := transformEndpoints(eps)
+	if err != nil {
+		return nil, nil, err
+	}
+	eps, synthetic, err := syntheticEndpoints(eps, config.MicroserviceDefinition.ServiceDescription.Instance.Synthetic)
+	if err != nil {
+		return nil, nil, err
+	}
+	md := map[string]string{chassisKey(MDSecure): strconv.FormatBool(secureEndpoints(eps))}
+	if synthetic {
+		// synthetic instances are filtered out by consumers in production
+		md[chassisKey(MDSynthetic)] = "true"
+	}
+	if config.GetRegistratorEndpointScheme() {
+		eps = schemedEndpoints(eps)
+	}
+	return eps, md, nil
+}
+
+// assembleInstance builds self instance from config as it is sent to registry,
+// along with the checked instance properties merged into its metadata
+func assembleInstance() (*MicroServiceInstance, map[string]string, error) {
+	service := config.MicroserviceDefinition
+	eps, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	if err != nil {
+		return nil, nil, err
+	}
+	lager.Logger.Infof("service support protocols %s", config.GlobalDefinition.Cse.Protocols)
+	if advertised := instanceEndpoints(); advertised != nil {
+		eps = advertised
+	}
+	eps, epsMD, err := publishedEndpoints(eps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	md, err := buildInstanceMetadata()
+	if err != nil {
+		lager.Logger.Errorf("Build instance metadata failed: %s", err)
+		return nil, nil, err
+	}
+	for k, v := range epsMD {
+		md[k] = v
+	}
+	instanceProperties, err := checkReservedKeys(userMetadata(service.ServiceDescription.InstanceProperties))
+	if err != nil {
+		lager.Logger.Errorf("Check instance properties failed: %s", err)
+		return nil, nil, err
+	}
+	// registry holds properties and chassis managed metadata in one set
+	for k, v := range instanceProperties {
+		md[k] = v
+	}
+	status, err := initialStatus(service.ServiceDescription.Instance.InitialStatus)
+	if err != nil {
+		lager.Logger.Errorf("Get instance status failed: %s", err)
+		return nil, nil, err
+	}
+
+	microServiceInstance := &MicroServiceInstance{
+		EndpointsMap: eps,
+		HostName:     runtime.HostName,
+		Status:       status,
+		Metadata:     md,
+	}
+
+	var dInfo = new(DataCenterInfo)
+	if config.GlobalDefinition.DataCenter.AvailableZone != "" {
+		if err := validAvailableZone(config.GlobalDefinition.DataCenter.AvailableZone); err != nil {
+			lager.Logger.Errorf("Get data center info failed: %s", err)
+			return nil, nil, err
+		}
+		name := config.GlobalDefinition.DataCenter.Name
+		if name == "" {
+			// keep zone info for zone aware routing even if data center is not named
+			name = config.GetRegistratorDataCenterPlaceholder()
+		}
+		dInfo.Name = name
+		dInfo.Region = name
+		dInfo.AvailableZone = config.GlobalDefinition.DataCenter.AvailableZone
+		microServiceInstance.DataCenterInfo = dInfo
+	}
+	return microServiceInstance, instanceProperties, nil
+}
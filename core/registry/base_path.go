@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/util"
+)
+
+// basePathProtocols are the protocols which can be mounted under a base path
+var basePathProtocols = map[string]bool{
+	common.ProtocolRest: true,
+}
+
+// basePathKey returns the metadata key of the base path of protocol name, like basePath.rest
+func basePathKey(name string) string {
+	return MDBasePath + "." + name
+}
+
+// validBasePath checks the base path begins with slash
+func validBasePath(p string) error {
+	if !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("base path [%s] must begin with /", p)
+	}
+	return nil
+}
+
+// basePaths returns the base path to advertise of each protocol,
+// base paths of protocols which do not support it are ignored with a warning
+func basePaths(protocols map[string]model.Protocol) (map[string]string, error) {
+	paths := make(map[string]string)
+	for name, p := range protocols {
+		if p.BasePath == "" {
+			continue
+		}
+		if protocol, _, err := util.ParsePortName(name); err != nil || !basePathProtocols[protocol] {
+			lager.Logger.Warnf("base path of [%s] is ignored, protocol does not support it", name)
+			continue
+		}
+		if err := validBasePath(p.BasePath); err != nil {
+			return nil, err
+		}
+		paths[name] = p.BasePath
+	}
+	return paths, nil
+}
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasePathMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:            {Listen: "127.0.0.1:8080", BasePath: "/api/v2"},
+		common.ProtocolRest + "-admin": {Listen: "127.0.0.1:8081", BasePath: "/admin"},
+		common.ProtocolHighway:         {Listen: "127.0.0.1:9090", BasePath: "/ignored"},
+	}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md := r.instances[0].Metadata
+	assert.Equal(t, "/api/v2", md["basePath.rest"])
+	assert.Equal(t, "/admin", md["basePath.rest-admin"])
+	_, ok := md["basePath.highway"]
+	assert.False(t, ok)
+
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"basePath.rest": "/user"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "/api/v2", GetSelfMetadata()["basePath.rest"])
+}
+
+func TestBasePathValidation(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1:8080", BasePath: "api"},
+	}
+	err := ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cse.protocols.rest.basePath")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Empty(t, r.instances)
+}
@@ -2,175 +2,254 @@ package registry
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/go-chassis/go-chassis/core/common"
 	"github.com/go-chassis/go-chassis/core/config"
-	"github.com/go-chassis/go-chassis/core/config/schema"
 	"github.com/go-chassis/go-chassis/core/lager"
-	"github.com/go-chassis/go-chassis/core/metadata"
 	"github.com/go-chassis/go-chassis/pkg/runtime"
 )
 
 var errEmptyServiceIDFromRegistry = errors.New("got empty serviceID from registry")
 
+// ErrEmptyInstanceID means registry registered the instance without error but returned an empty instanceID,
+// it is retried like other registry failures
+var ErrEmptyInstanceID = errors.New("got empty instanceID from registry")
+
+// ErrCrossAppNotAccepted means registry did not accept allowCrossApp of a full scope service
+var ErrCrossAppNotAccepted = errors.New("registry did not accept allowCrossApp for full scope")
+
 // microServiceDependencies micro-service dependencies
 var microServiceDependencies *MicroServiceDependency
 
-// InstanceEndpoints instance endpoints
+// InstanceEndpoints instance endpoints, set them before registration, UpdateAdvertisedEndpoints changes them afterwards
 var InstanceEndpoints map[string]string
 
+// nowFunc is the clock used wherever registration records time,
+// tests and replay can replace it with a fixed clock
+var nowFunc = time.Now
+
+// RegistrationRunner runs the registration of self micro-service and instance
+// with the registrator and service discovery it holds,
+// tests can construct it with fakes instead of replacing the default ones
+type RegistrationRunner struct {
+	Registrator Registrator
+	Discovery   ServiceDiscovery
+}
+
+// NewRegistrationRunner returns a registration runner using r and d
+func NewRegistrationRunner(r Registrator, d ServiceDiscovery) *RegistrationRunner {
+	return &RegistrationRunner{
+		Registrator: r,
+		Discovery:   d,
+	}
+}
+
+// defaultRunner returns a registration runner using DefaultRegistrator and DefaultServiceDiscoveryService
+func defaultRunner() *RegistrationRunner {
+	return NewRegistrationRunner(DefaultRegistrator, DefaultServiceDiscoveryService)
+}
+
 // RegisterMicroservice register micro-service
 func RegisterMicroservice() error {
-	service := config.MicroserviceDefinition
-	if e := service.ServiceDescription.Environment; e != "" {
-		lager.Logger.Infof("Microservice environment: [%s]", e)
-	} else {
-		lager.Logger.Debug("No microservice environment defined")
+	return defaultRunner().RegisterMicroservice()
+}
+
+// RegisterMicroserviceInstances register micro-service instances
+func RegisterMicroserviceInstances() error {
+	return defaultRunner().RegisterMicroserviceInstances()
+}
+
+// RegisterMicroserviceUsing register micro-service with r instead of DefaultRegistrator
+func RegisterMicroserviceUsing(r Registrator) error {
+	return NewRegistrationRunner(r, DefaultServiceDiscoveryService).RegisterMicroservice()
+}
+
+// RegisterMicroserviceInstancesUsing register micro-service instances with r instead of DefaultRegistrator
+func RegisterMicroserviceInstancesUsing(r Registrator) error {
+	return NewRegistrationRunner(r, DefaultServiceDiscoveryService).RegisterMicroserviceInstances()
+}
+
+// verifyScope verifies scope of self micro-service with DefaultServiceDiscoveryService
+func verifyScope() error {
+	return defaultRunner().verifyScope()
+}
+
+// RegisterMicroservice register micro-service,
+// in instance first order it is registered along with self instance instead
+func (r *RegistrationRunner) RegisterMicroservice() (err error) {
+	if config.GetRegistratorSkip() {
+		skipRegistration(PhaseService)
+		return nil
 	}
-	microServiceDependencies = &MicroServiceDependency{}
-	schemas, err := schema.GetSchemaIDs(service.ServiceDescription.Name)
+	if _, ok := r.instanceFirst(); ok {
+		lager.Logger.Info("Micro service will be registered along with instance")
+		return nil
+	}
+	defer func() { countRegistration(PhaseService, err) }()
+	microservice, err := prepareMicroService()
 	if err != nil {
-		lager.Logger.Warnf("No schemas file for microservice [%s].", service.ServiceDescription.Name)
-		schemas = make([]string, 0)
-	}
-	if service.ServiceDescription.Level == "" {
-		service.ServiceDescription.Level = common.DefaultLevel
-	}
-	if service.ServiceDescription.Properties == nil {
-		service.ServiceDescription.Properties = make(map[string]string)
-	}
-	framework := metadata.NewFramework()
-
-	svcPaths := service.ServiceDescription.ServicePaths
-	var regpaths []ServicePath
-	for _, svcPath := range svcPaths {
-		var regpath ServicePath
-		regpath.Path = svcPath.Path
-		regpath.Property = svcPath.Property
-		regpaths = append(regpaths, regpath)
-	}
-	microservice := &MicroService{
-		ServiceID:   runtime.ServiceID,
-		AppID:       runtime.App,
-		ServiceName: service.ServiceDescription.Name,
-		Version:     service.ServiceDescription.Version,
-		Paths:       regpaths,
-		Environment: service.ServiceDescription.Environment,
-		Status:      common.DefaultStatus,
-		Level:       service.ServiceDescription.Level,
-		Schemas:     schemas,
-		Framework: &Framework{
-			Version: framework.Version,
-			Name:    framework.Name,
-		},
-		RegisterBy: framework.Register,
-		Metadata:   make(map[string]string),
-		// TODO allows to customize microservice alias
-		Alias: "",
-	}
-	//update metadata
-	if len(microservice.Alias) == 0 {
-		// if the microservice is allowed to be called by consumers with different appId,
-		// this means that the governance configuration of the consumer side needs to
-		// support key format with appid, like 'cse.loadbalance.{alias}.strategy.name'.
-		microservice.Alias = microservice.AppID + ":" + microservice.ServiceName
-	}
-	if config.GetRegistratorScope() == common.ScopeFull {
-		microservice.Metadata["allowCrossApp"] = common.TRUE
-		service.ServiceDescription.Properties["allowCrossApp"] = common.TRUE
-	} else {
-		service.ServiceDescription.Properties["allowCrossApp"] = common.FALSE
+		return err
 	}
-	lager.Logger.Debugf("Update micro service properties%v", service.ServiceDescription.Properties)
-	lager.Logger.Infof("Framework registered is [ %s:%s ]", framework.Name, framework.Version)
-	lager.Logger.Infof("Micro service registered by [ %s ]", framework.Register)
 
-	sid, err := DefaultRegistrator.RegisterService(microservice)
+	var sid string
+	key := idempotencyKey(PhaseService)
+	err = traceRegistration(SpanRegisterService, func() (e error) {
+		sid, e = r.registerService(key, microservice)
+		return
+	})
 	if err != nil {
 		lager.Logger.Errorf("Register [%s] failed: %s", microservice.ServiceName, err)
 		return err
 	}
+	if sid != "" {
+		finishIdempotencyKey(PhaseService)
+	}
+	return r.serviceRegistered(sid, microservice)
+}
+
+// serviceRegistered records the id of registered self micro-service and verifies its scope, then uploads its schemas and route rules
+func (r *RegistrationRunner) serviceRegistered(sid string, microservice *MicroService) error {
 	if sid == "" {
 		lager.Logger.Error(errEmptyServiceIDFromRegistry.Error())
 		return errEmptyServiceIDFromRegistry
 	}
-	runtime.ServiceID = sid
-	lager.Logger.Infof("Register [%s/%s] success", runtime.ServiceID, microservice.ServiceName)
+	oldID := setSelfServiceID(sid)
+	lager.Logger.Infof("Register [%s/%s] success", sid, microservice.ServiceName)
+	if oldID != "" && oldID != sid {
+		serviceIDChanged(oldID, sid)
+	}
 
-	for _, schemaID := range schemas {
-		schemaInfo := schema.DefaultSchemaIDsMap[schemaID]
-		DefaultRegistrator.AddSchemas(sid, schemaID, schemaInfo)
+	saveCheckpoint(checkpoint{ServiceID: sid})
+	if err := r.verifyScope(); err != nil {
+		lager.Logger.Errorf("Verify scope failed: %s", err)
+		return err
 	}
 
-	return nil
+	r.registerSchemas(sid, microservice.Schemas)
+	if err := r.addDependencies(sid, microServiceDependencies); err != nil {
+		return err
+	}
+	return r.publishRouteRules(sid, config.MicroserviceDefinition.ServiceDescription.RouteRules)
 }
 
-// RegisterMicroserviceInstances register micro-service instances
-func RegisterMicroserviceInstances() error {
-	lager.Logger.Info("Start to register instance.")
-	service := config.MicroserviceDefinition
-	var err error
+// levels of registration banner logs
+const (
+	BannerLogInfo  = "info"
+	BannerLogDebug = "debug"
+	BannerLogOff   = "off"
+)
 
-	sid, err := DefaultServiceDiscoveryService.GetMicroServiceID(runtime.App, service.ServiceDescription.Name, service.ServiceDescription.Version, service.ServiceDescription.Environment)
-	if err != nil {
-		lager.Logger.Errorf("Get service failed, key: %s:%s:%s, err %s",
-			runtime.App,
-			service.ServiceDescription.Name,
-			service.ServiceDescription.Version, err)
-		return err
+// logBanner logs framework information of registration at the level of bannerLogLevel, info by default
+func logBanner(format string, args ...interface{}) {
+	switch strings.ToLower(config.GetRegistratorBannerLogLevel()) {
+	case BannerLogOff:
+	case BannerLogDebug:
+		lager.Logger.Debugf(format, args...)
+	default:
+		lager.Logger.Infof(format, args...)
 	}
-	eps, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+}
+
+// verifyScope reads back the registered service to make sure allowCrossApp is accepted,
+// it only takes effect when scope is full, verifyScope is enabled and registration is not disabled
+func (r *RegistrationRunner) verifyScope() error {
+	if config.GetRegistratorScope() != common.ScopeFull || !config.GetRegistratorVerifyScope() || registrationSkipped() {
+		return nil
+	}
+	if r.Discovery == nil {
+		return errors.New("service discovery is not enabled, can not verify scope")
+	}
+	ms, err := r.Discovery.GetMicroService(runtime.ServiceID)
 	if err != nil {
 		return err
 	}
-	lager.Logger.Infof("service support protocols %s", config.GlobalDefinition.Cse.Protocols)
-	if InstanceEndpoints != nil {
-		eps = InstanceEndpoints
+	if ms == nil || ms.Metadata[chassisKey(MDAllowCrossApp)] != common.TRUE {
+		return ErrCrossAppNotAccepted
 	}
+	return nil
+}
 
-	microServiceInstance := &MicroServiceInstance{
-		EndpointsMap: eps,
-		HostName:     runtime.HostName,
-		Status:       common.DefaultStatus,
-		Metadata:     map[string]string{"nodeIP": config.NodeIP},
+// RegisterMicroserviceInstances register micro-service instances
+func (r *RegistrationRunner) RegisterMicroserviceInstances() (err error) {
+	if config.GetRegistratorSkip() {
+		skipRegistration(PhaseInstance)
+		return nil
 	}
+	defer func() { countRegistration(PhaseInstance, err) }()
+	lager.Logger.Info("Start to register instance.")
+	service := config.MicroserviceDefinition
 
-	var dInfo = new(DataCenterInfo)
-	if config.GlobalDefinition.DataCenter.Name != "" && config.GlobalDefinition.DataCenter.AvailableZone != "" {
-		dInfo.Name = config.GlobalDefinition.DataCenter.Name
-		dInfo.Region = config.GlobalDefinition.DataCenter.Name
-		dInfo.AvailableZone = config.GlobalDefinition.DataCenter.AvailableZone
-		microServiceInstance.DataCenterInfo = dInfo
+	var sid string
+	var microservice *MicroService
+	first, instanceFirst := r.instanceFirst()
+	if instanceFirst {
+		if microservice, err = prepareMicroService(); err != nil {
+			return err
+		}
+	} else {
+		key := registrationKey()
+		sid, err = r.lookupOrRegisterMicroServiceID(key.App, key.Name, key.Version, key.Env)
+		if err != nil {
+			lager.Logger.Errorf("Get service failed, key: %s, err %s", key, err)
+			return err
+		}
+	}
+	microServiceInstance, selfMD, instanceProperties, err := prepareInstance()
+	if err != nil {
+		return err
 	}
+	status := microServiceInstance.Status
 
-	instanceID, err := DefaultRegistrator.RegisterServiceInstance(sid, microServiceInstance)
+	var instanceID string
+	key := idempotencyKey(PhaseInstance)
+	err = traceRegistration(SpanRegisterInstance, func() (e error) {
+		if instanceFirst {
+			sid, instanceID, e = callForIDs(OpRegisterInstance, func() (string, string, error) {
+				return first.RegisterInstanceFirst(microservice, microServiceInstance)
+			})
+			return
+		}
+		instanceID, e = r.registerInstance(key, sid, microServiceInstance)
+		return
+	})
 	if err != nil {
-		lager.Logger.Errorf("Register instance failed, serviceID: %s, err %s", err)
+		lager.Logger.Errorf("Register instance failed, serviceID: %s, err %s", sid, err)
 		return err
 	}
+	if instanceID == "" {
+		lager.Logger.Errorf("Register instance failed, serviceID: %s, err %s", sid, ErrEmptyInstanceID)
+		return ErrEmptyInstanceID
+	}
+	if instanceFirst {
+		if err = r.serviceRegistered(sid, microservice); err != nil {
+			return err
+		}
+	}
+	finishIdempotencyKey(PhaseInstance)
 	//Set to runtime
-	runtime.InstanceID = instanceID
-	runtime.InstanceStatus = runtime.StatusRunning
-	if service.ServiceDescription.InstanceProperties != nil {
-		if err := DefaultRegistrator.UpdateMicroServiceInstanceProperties(sid, instanceID, service.ServiceDescription.InstanceProperties); err != nil {
+	setSelfInstanceID(instanceID)
+	runtime.InstanceStatus = status
+	if status == runtime.StatusOutOfService {
+		lager.Logger.Warnf("Instance is registered %s, call EnableInstance to put it into rotation", status)
+	}
+	// registry replaces the whole instance metadata with properties, so the registered metadata is pushed
+	if service.ServiceDescription.InstanceProperties != nil || config.GetRegistratorClearInstanceProperties() {
+		if err := updateInstanceProperties(r.Registrator, sid, instanceID, microServiceInstance.Metadata); err != nil {
 			lager.Logger.Errorf("UpdateMicroServiceInstanceProperties failed, microServiceID/instanceID = %s/%s.", sid, instanceID)
 			return err
 		}
 		lager.Logger.Debugf("UpdateMicroServiceInstanceProperties success, microServiceID/instanceID = %s/%s.", sid, instanceID)
 	}
+	setSelfMetadata(selfMD, instanceProperties)
+	setSelfEndpoints(instanceID, microServiceInstance.EndpointsMap)
 
-	value, _ := SelfInstancesCache.Get(microServiceInstance.ServiceID)
-	instanceIDs, _ := value.([]string)
-	var isRepeat bool
-	for _, va := range instanceIDs {
-		if va == instanceID {
-			isRepeat = true
-		}
-	}
-	if !isRepeat {
-		instanceIDs = append(instanceIDs, instanceID)
-	}
-	SelfInstancesCache.Set(sid, instanceIDs, 0)
+	addSelfInstanceID(sid, instanceID)
+	reconcileLateInstances(r.Registrator, instanceID)
+	r.cleanPreviousInstance(sid, instanceID)
+	saveCheckpoint(checkpoint{ServiceID: sid, InstanceID: instanceID})
 	lager.Logger.Infof("Register instance success, serviceID/instanceID: %s/%s.", sid, instanceID)
+	r.preloadProviders(sid)
 	return nil
 }
@@ -0,0 +1,702 @@
+package registry
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/core/metadata"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/go-chassis/go-chassis/pkg/util/tags"
+	lagerlib "github.com/go-chassis/paas-lager/third_party/forked/cloudfoundry/lager"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRegistrator records what bootstrap sends to registry
+type fakeRegistrator struct {
+	mu         sync.Mutex
+	sid        string
+	iid        string
+	err        error
+	schemaErr  error
+	delay      map[string]time.Duration
+	services   []*MicroService
+	instances  []*MicroServiceInstance
+	schemas    map[string]string
+	properties map[string]string
+	status     []string
+	// unregistered records sid/iid of unregistered instances
+	unregistered []string
+	heartbeats   []time.Time
+	hbErr        error
+	// propertyUpdates counts instance properties updates
+	propertyUpdates int
+	propErr         error
+}
+
+func newFakeRegistrator() *fakeRegistrator {
+	return &fakeRegistrator{
+		sid:     "sid",
+		iid:     "iid",
+		delay:   make(map[string]time.Duration),
+		schemas: make(map[string]string),
+	}
+}
+
+func (f *fakeRegistrator) wait(op string) {
+	if d := f.delay[op]; d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (f *fakeRegistrator) Close() error { return nil }
+func (f *fakeRegistrator) RegisterService(ms *MicroService) (string, error) {
+	f.wait(OpRegisterService)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.services = append(f.services, ms)
+	return f.sid, f.err
+}
+func (f *fakeRegistrator) RegisterServiceInstance(sid string, ins *MicroServiceInstance) (string, error) {
+	f.wait(OpRegisterInstance)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.instances = append(f.instances, ins)
+	return f.iid, f.err
+}
+func (f *fakeRegistrator) RegisterServiceAndInstance(ms *MicroService, ins *MicroServiceInstance) (string, string, error) {
+	return f.sid, f.iid, f.err
+}
+func (f *fakeRegistrator) Heartbeat(sid, iid string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.heartbeats = append(f.heartbeats, time.Now())
+	return f.hbErr == nil, f.hbErr
+}
+func (f *fakeRegistrator) AddDependencies(dep *MicroServiceDependency) error { return nil }
+func (f *fakeRegistrator) UnRegisterMicroServiceInstance(sid, iid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unregistered = append(f.unregistered, sid+"/"+iid)
+	return nil
+}
+func (f *fakeRegistrator) UpdateMicroServiceInstanceStatus(sid, iid, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = append(f.status, status)
+	return f.err
+}
+func (f *fakeRegistrator) UpdateMicroServiceProperties(sid string, properties map[string]string) error {
+	return nil
+}
+func (f *fakeRegistrator) UpdateMicroServiceInstanceProperties(sid, iid string, properties map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.propertyUpdates++
+	if f.propErr != nil {
+		return f.propErr
+	}
+	f.properties = properties
+	return nil
+}
+func (f *fakeRegistrator) AddSchemas(sid, schemaName, schemaInfo string) error {
+	f.wait(OpAddSchemas)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.schemaErr != nil {
+		return f.schemaErr
+	}
+	f.schemas[schemaName] = schemaInfo
+	return nil
+}
+
+// fakeDiscovery serves the self service from memory
+type fakeDiscovery struct {
+	sid      string
+	appID    string
+	err      error
+	services map[string]*MicroService
+
+	mu        sync.Mutex
+	found     []string
+	findDelay time.Duration
+}
+
+func (f *fakeDiscovery) GetMicroServiceID(appID, microServiceName, version, env string) (string, error) {
+	f.appID = appID
+	return f.sid, f.err
+}
+func (f *fakeDiscovery) GetAllMicroServices() ([]*MicroService, error) { return nil, nil }
+func (f *fakeDiscovery) GetMicroService(microServiceID string) (*MicroService, error) {
+	return f.services[microServiceID], f.err
+}
+func (f *fakeDiscovery) GetMicroServiceInstances(consumerID, providerID string) ([]*MicroServiceInstance, error) {
+	return nil, nil
+}
+func (f *fakeDiscovery) FindMicroServiceInstances(consumerID, microServiceName string, tags utiltags.Tags) ([]*MicroServiceInstance, error) {
+	time.Sleep(f.findDelay)
+	f.mu.Lock()
+	f.found = append(f.found, microServiceName+":"+tags.Version())
+	f.mu.Unlock()
+	return nil, nil
+}
+func (f *fakeDiscovery) AutoSync()    {}
+func (f *fakeDiscovery) Close() error { return nil }
+
+// initBootstrapEnv prepares a minimal config for bootstrap and installs fakes
+func initBootstrapEnv() (*fakeRegistrator, *fakeDiscovery) {
+	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	config.GlobalDefinition = &model.GlobalCfg{
+		DataCenter: &model.DataCenterInfo{},
+	}
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1:8080"},
+	}
+	config.MicroserviceDefinition = &model.MicroserviceCfg{
+		ServiceDescription: model.MicServiceStruct{
+			Name:    "TestService",
+			Version: "0.0.1",
+		},
+	}
+	runtime.App = common.DefaultApp
+	setSelfServiceID("")
+	setSelfInstanceID("")
+	InstanceEndpoints = nil
+	// keep loopback listen addresses as they are
+	localIPFunc = func() string { return "" }
+	lateMu.Lock()
+	lateInstances = nil
+	lateMu.Unlock()
+	enableRegistryCache()
+
+	r := newFakeRegistrator()
+	d := &fakeDiscovery{sid: r.sid, services: make(map[string]*MicroService)}
+	DefaultRegistrator = r
+	DefaultServiceDiscoveryService = d
+	return r, d
+}
+
+func TestRegisterMicroservice(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "sid", runtime.ServiceID)
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, "default:TestService", r.services[0].Alias)
+
+	r.sid = ""
+	assert.Equal(t, errEmptyServiceIDFromRegistry, RegisterMicroservice())
+}
+
+func TestRegisterMicroserviceInstances(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "iid", runtime.InstanceID)
+	assert.Equal(t, 1, len(r.instances))
+	assert.Equal(t, "127.0.0.1:8080", r.instances[0].EndpointsMap[common.ProtocolRest])
+}
+
+func TestRegisterEmptyInstanceID(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	runtime.InstanceID = ""
+	r.iid = ""
+	assert.Equal(t, ErrEmptyInstanceID, RegisterMicroserviceInstances())
+	assert.Equal(t, "", runtime.InstanceID)
+	assert.Empty(t, selfInstanceIDs(r.sid))
+	assert.True(t, DefaultRetryClassifier(ErrEmptyInstanceID), "empty instanceID is retried")
+
+	r.iid = "iid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "iid", runtime.InstanceID)
+}
+
+func TestReRegisterSelfInstance(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	config.MicroserviceDefinition.ServiceDescription.Instance.Capacity = 8
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1"}
+	defer func() { endpointMapTransformers = nil }()
+	AddEndpointMapTransformer(func(eps map[string]string) (map[string]string, error) {
+		return map[string]string{"rest": "10.0.0.1:80"}, nil
+	})
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{MDTrafficPercent: "20"}))
+	runtime.InstanceStatus = runtime.StatusOutOfService
+
+	assert.NoError(t, reRegisterSelfMSI(r.sid, r.iid))
+	ins := r.instances[1]
+	assert.Equal(t, r.iid, ins.InstanceID)
+	assert.Equal(t, runtime.StatusOutOfService, ins.Status)
+	assert.Equal(t, map[string]string{"rest": "10.0.0.1:80"}, ins.EndpointsMap)
+	assert.Equal(t, "8", ins.Metadata[MDCapacity], "chassis metadata is registered again")
+	assert.Equal(t, "20", ins.Metadata[MDTrafficPercent], "runtime update is kept")
+	assert.Equal(t, "z1", ins.Metadata["zone"])
+	assert.Equal(t, unsignedDigest(t, ins, ins.Metadata), ins.Metadata[MDSignature])
+	assert.Equal(t, "20", GetSelfMetadata()[MDTrafficPercent])
+}
+
+func TestVerifyScope(t *testing.T) {
+	r, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, common.TRUE, r.services[0].Metadata["allowCrossApp"])
+
+	// registry drops the metadata
+	d.services["sid"] = &MicroService{ServiceID: "sid", Metadata: map[string]string{}}
+	assert.NoError(t, verifyScope(), "verification is opt-in")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = true
+	assert.Equal(t, ErrCrossAppNotAccepted, verifyScope())
+
+	d.services["sid"] = &MicroService{ServiceID: "sid", Metadata: r.services[0].Metadata}
+	assert.NoError(t, verifyScope())
+
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeApp
+	d.services["sid"] = &MicroService{ServiceID: "sid", Metadata: map[string]string{}}
+	assert.NoError(t, verifyScope())
+}
+
+func TestVerifyScopeEncoded(t *testing.T) {
+	r, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = true
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = MetadataEncodingBase64
+	d.services["sid"] = &MicroService{ServiceID: "sid", Metadata: map[string]string{MDAllowCrossApp: common.TRUE}}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, common.TRUE, r.services[0].Metadata[MDAllowCrossApp], "registry reads it as it is")
+}
+
+func TestRegisterWithFixedClock(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	fixed := time.Date(2018, 12, 20, 8, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return fixed }
+	defer func() { nowFunc = time.Now }()
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, "startTime", "opt-in")
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.StartTime = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "2018-12-20T08:00:00Z", r.instances[1].Metadata["startTime"])
+
+	hb := &HeartbeatService{instances: make(map[string]*HeartbeatTask)}
+	hb.AddTask("sid", "iid")
+	assert.Equal(t, fixed, hb.instances["sid/iid"].Time)
+}
+
+func TestRegistrationRunner(t *testing.T) {
+	initBootstrapEnv()
+	DefaultRegistrator = nil
+	DefaultServiceDiscoveryService = nil
+
+	r := newFakeRegistrator()
+	r.sid, r.iid = "runnerSid", "runnerIid"
+	d := &fakeDiscovery{sid: r.sid}
+	runner := NewRegistrationRunner(r, d)
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.NoError(t, runner.RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, 1, len(r.instances))
+	assert.Equal(t, "runnerSid", runtime.ServiceID)
+	assert.Equal(t, "runnerIid", runtime.InstanceID)
+	assert.Nil(t, DefaultRegistrator)
+	assert.Nil(t, DefaultServiceDiscoveryService)
+}
+
+func TestRegisterUsing(t *testing.T) {
+	global, d := initBootstrapEnv()
+	r := newFakeRegistrator()
+	r.sid, r.iid = "usingSid", "usingIid"
+	d.sid = r.sid
+	assert.NoError(t, RegisterMicroserviceUsing(r))
+	assert.NoError(t, RegisterMicroserviceInstancesUsing(r))
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, 1, len(r.instances))
+	assert.Equal(t, "usingIid", runtime.InstanceID)
+	assert.Equal(t, global, DefaultRegistrator)
+	assert.Empty(t, global.services)
+	assert.Empty(t, global.instances)
+}
+
+func TestRegisterInstanceProperties(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	// not configured, registry side properties are kept
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Nil(t, r.properties)
+
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "z1", r.properties["zone"])
+	assert.Equal(t, r.instances[1].Metadata, r.properties, "registered metadata is pushed as a whole")
+	assert.Contains(t, r.properties, MDNodeIP, "chassis managed keys are kept")
+	assert.Equal(t, r.properties, GetSelfMetadata())
+
+	// configured empty
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.properties, "zone")
+	assert.Contains(t, r.properties, MDNodeIP)
+
+	r.properties = map[string]string{"zone": "z1"}
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = nil
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{"zone": "z1"}, r.properties)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ClearInstanceProperties = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.properties, "zone")
+	assert.Contains(t, r.properties, MDNodeIP)
+}
+
+func TestClearInstanceProperties(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "z1", r.properties["zone"])
+
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = nil
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ClearInstanceProperties = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.properties, "zone", "stale user key is cleared")
+	for _, k := range []string{MDNodeIP, MDSecure, MDSignature} {
+		assert.Contains(t, r.properties, chassisKey(k), "chassis key %s survives", k)
+	}
+	assert.Equal(t, r.instances[1].Metadata, r.properties)
+}
+
+func TestRegisterWithAppIDOverride(t *testing.T) {
+	r, d := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, common.DefaultApp, r.services[0].AppID)
+	assert.Equal(t, common.DefaultApp, d.appID)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.AppID = "tooling"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "tooling", r.services[1].AppID)
+	assert.Equal(t, "tooling:TestService", r.services[1].Alias)
+	assert.Equal(t, "tooling", d.appID)
+	assert.Equal(t, common.DefaultApp, runtime.App)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.AppID = " "
+	assert.Error(t, RegisterMicroservice())
+	assert.Equal(t, 2, len(r.services))
+}
+
+func TestRegisterWithDisplayName(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.DisplayName = "Test Service"
+	assert.NoError(t, RegisterMicroservice())
+	ms := r.services[0]
+	assert.Equal(t, "Test Service", ms.Metadata[MDDisplayName])
+	assert.Equal(t, "TestService", ms.ServiceName)
+	assert.Equal(t, "default:TestService", ms.Alias)
+	assert.Equal(t, "TestService:0.0.1:default", Microservice2ServiceKeyStr(ms))
+
+	config.MicroserviceDefinition.ServiceDescription.DisplayName = "  "
+	assert.Error(t, RegisterMicroservice())
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterWithCategories(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	_, ok := r.services[0].Metadata[MDCategories]
+	assert.False(t, ok)
+
+	config.MicroserviceDefinition.ServiceDescription.Categories = []string{"payment", " order "}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "payment,order", r.services[1].Metadata[MDCategories])
+
+	config.MicroserviceDefinition.ServiceDescription.Categories = []string{"payment", " "}
+	assert.Error(t, RegisterMicroservice())
+	config.MicroserviceDefinition.ServiceDescription.Categories = []string{"payment,order"}
+	assert.Error(t, RegisterMicroservice())
+	assert.Equal(t, 2, len(r.services))
+}
+
+func TestRegisterWithMinClientVersion(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.MinClientVersion = "1.2.0"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "1.2.0", r.services[0].Metadata[MDMinClientVersion])
+
+	config.MicroserviceDefinition.ServiceDescription.MinClientVersion = "v1.2"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.minClientVersion")
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterWithLBStrategy(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetLBStrategyChecker(func(name string) bool { return name == "Random" })
+	defer SetLBStrategyChecker(nil)
+	config.MicroserviceDefinition.ServiceDescription.LBStrategy = "Random"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "Random", r.services[0].Metadata[MDLBStrategy])
+
+	config.MicroserviceDefinition.ServiceDescription.LBStrategy = "Fastest"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.lbStrategy")
+	assert.Equal(t, 1, len(r.services))
+
+	SetLBStrategyChecker(nil)
+	assert.NoError(t, RegisterMicroservice(), "strategies are not checked without checker")
+	assert.Equal(t, "Fastest", r.services[1].Metadata[MDLBStrategy])
+}
+
+func TestRegisterWithOwner(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Strict = true
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.owner")
+	assert.Contains(t, err.Error(), "service_description.contact")
+	assert.Equal(t, 0, len(r.services))
+
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Owner = "payment-team"
+	desc.Contact = "payment-oncall@example.com"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "payment-team", r.services[0].Metadata[MDOwner])
+	assert.Equal(t, "payment-oncall@example.com", r.services[0].Metadata[MDContact])
+}
+
+func TestRegisterWithTier(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.Tier = "gold"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "gold", r.services[0].Metadata[MDTier])
+
+	config.MicroserviceDefinition.ServiceDescription.Tier = "platinum"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.tier")
+	assert.Equal(t, 1, len(r.services))
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Tiers = []string{"platinum", "gold"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "platinum", r.services[1].Metadata[MDTier])
+}
+
+func TestRegisterWithDocsURL(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.DocsURL = "https://docs.example.com/payment"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "https://docs.example.com/payment", r.services[0].Metadata[MDDocsURL])
+
+	for _, u := range []string{"docs.example.com/payment", "ftp://docs.example.com", "https://", "://bad"} {
+		config.MicroserviceDefinition.ServiceDescription.DocsURL = u
+		err := RegisterMicroservice()
+		assert.Error(t, err, u)
+		assert.Contains(t, err.Error(), "service_description.docsURL")
+	}
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterDeprecated(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Deprecated = true
+	desc.DeprecationMessage = " use PaymentV2 "
+	desc.SunsetDate = "2027-03-31"
+	assert.NoError(t, RegisterMicroservice())
+	md := r.services[0].Metadata
+	assert.Equal(t, "true", md[MDDeprecated])
+	assert.Equal(t, "use PaymentV2", md[MDDeprecationMsg])
+	assert.Equal(t, "2027-03-31", md[MDSunsetDate])
+
+	desc.SunsetDate = "31/03/2027"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.sunsetDate")
+
+	desc.Deprecated = false
+	desc.SunsetDate = "2027-03-31"
+	err = RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.deprecated")
+
+	desc.DeprecationMessage, desc.SunsetDate = "", ""
+	assert.NoError(t, RegisterMicroservice())
+	_, ok := r.services[1].Metadata[MDDeprecated]
+	assert.False(t, ok)
+}
+
+func TestRegisterWithMaxConcurrency(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.MaxConcurrency = 200
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "200", r.services[0].Metadata[MDMaxConcurrency])
+	assert.Equal(t, "200", r.instances[0].Metadata[MDMaxConcurrency])
+
+	config.MicroserviceDefinition.ServiceDescription.MaxConcurrency = -1
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.maxConcurrency")
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterWithRateLimitHint(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NotContains(t, r.services[0].Metadata, MDRateLimitHint)
+
+	config.MicroserviceDefinition.ServiceDescription.RateLimitHint = 500
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "500", r.services[1].Metadata[MDRateLimitHint])
+
+	config.MicroserviceDefinition.ServiceDescription.RateLimitHint = -5
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.rateLimitHint")
+	assert.Equal(t, 2, len(r.services))
+}
+
+func TestRegisterWithAuthSchemes(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.AuthSchemes = []string{"JWT", " mtls", "apikey"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "jwt,mtls,apikey", r.services[0].Metadata[MDAuthSchemes])
+
+	desc.AuthSchemes = []string{"jwt", "kerberos", "Jwt"}
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.authSchemes[1]: auth scheme [kerberos] is unknown")
+	assert.Contains(t, err.Error(), "service_description.authSchemes[2]: duplicated auth scheme [jwt]")
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterServicePaths(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.ServicePaths = []model.ServicePathStruct{
+		{Path: "orders", Property: map[string]string{"checksession": "false"}},
+		{Path: "/users"},
+		{Path: " /orders", Property: map[string]string{"checksession": "true", "auth": "jwt"}},
+	}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []ServicePath{
+		{Path: "/orders", Property: map[string]string{"checksession": "true", "auth": "jwt"}},
+		{Path: "/users"},
+	}, r.services[0].Paths)
+	assert.Equal(t, map[string]string{"checksession": "false"}, desc.ServicePaths[0].Property, "config is not modified")
+
+	desc.ServicePaths = append(desc.ServicePaths, model.ServicePathStruct{Path: " "})
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.paths[3].path")
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterWithEnvironments(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Environment = "development"
+	desc.Environments = []string{"testing", "development", "acceptance"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "development", r.services[0].Environment)
+	assert.Equal(t, "development,testing,acceptance", r.services[0].Metadata[MDEnvironments])
+
+	desc.Environments = []string{"testing", "bad env", "testing"}
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.environments[1]")
+	assert.Contains(t, err.Error(), "service_description.environments[2]: duplicated environment [testing]")
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestRegisterWithDataCenter(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Nil(t, r.instances[0].DataCenterInfo)
+
+	config.GlobalDefinition.DataCenter = &model.DataCenterInfo{Name: "dc", AvailableZone: "az1"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, &DataCenterInfo{Name: "dc", Region: "dc", AvailableZone: "az1"}, r.instances[1].DataCenterInfo)
+
+	// zone only
+	config.GlobalDefinition.DataCenter = &model.DataCenterInfo{AvailableZone: "az1"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, &DataCenterInfo{Name: "default", Region: "default", AvailableZone: "az1"}, r.instances[2].DataCenterInfo)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DataCenterPlaceholder = "unnamed"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, &DataCenterInfo{Name: "unnamed", Region: "unnamed", AvailableZone: "az1"}, r.instances[3].DataCenterInfo)
+
+	config.GlobalDefinition.DataCenter = &model.DataCenterInfo{Name: "dc"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Nil(t, r.instances[4].DataCenterInfo)
+}
+
+// levelSink records levels of logs containing "registered"
+type levelSink struct {
+	levels []lagerlib.LogLevel
+}
+
+func (s *levelSink) Log(level lagerlib.LogLevel, payload []byte) {
+	if strings.Contains(string(payload), "registered") {
+		s.levels = append(s.levels, level)
+	}
+}
+
+func TestBannerLogLevel(t *testing.T) {
+	initBootstrapEnv()
+	defer func(l lagerlib.Logger) { lager.Logger = l }(lager.Logger)
+	sink := &levelSink{}
+	lager.Logger = lagerlib.NewLogger("test")
+	lager.Logger.RegisterSink(sink)
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []lagerlib.LogLevel{lagerlib.INFO, lagerlib.INFO}, sink.levels)
+
+	sink.levels = nil
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.BannerLogLevel = "Debug"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []lagerlib.LogLevel{lagerlib.DEBUG, lagerlib.DEBUG}, sink.levels)
+
+	sink.levels = nil
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.BannerLogLevel = BannerLogOff
+	assert.NoError(t, RegisterMicroservice())
+	assert.Empty(t, sink.levels)
+}
+
+func TestRegisterWithWrongTypedSelfInstancesCache(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SelfInstancesCache.Set(r.sid, "not a slice", 0)
+	assert.NoError(t, RegisterMicroserviceInstances())
+	ids, ok := SelfInstancesCache.Get(r.sid)
+	assert.True(t, ok)
+	assert.Equal(t, []string{r.iid}, ids)
+
+	// prior instance ids are kept
+	r.iid = "anotherIid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	ids, _ = SelfInstancesCache.Get(r.sid)
+	assert.Equal(t, []string{"iid", "anotherIid"}, ids)
+}
+
+func TestRegisterWithEmptyFramework(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	f := metadata.NewFramework()
+	origin := *f
+	defer func() { *f = origin }()
+	f.SetName("")
+	f.SetVersion("")
+	f.SetRegister("")
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, &Framework{Name: metadata.SdkName, Version: DefaultFrameworkVersion}, r.services[0].Framework)
+	assert.Equal(t, metadata.SdkRegistrationComponent, r.services[0].RegisterBy)
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/metadata"
+)
+
+// build info of the binary, they are meant to be set with ldflags, e.g.
+// -ldflags "-X github.com/go-chassis/go-chassis/core/registry.BuildCommit=$(git rev-parse HEAD)"
+var (
+	BuildCommit string
+	BuildBranch string
+	BuildTime   string
+)
+
+// buildMetadata returns commit, branch and build time of the binary,
+// it only takes effect when instance.build.enabled is true, configured values take precedence over ldflags, empty ones are skipped
+func buildMetadata() map[string]string {
+	b := config.MicroserviceDefinition.ServiceDescription.Instance.Build
+	if !b.Enabled {
+		return nil
+	}
+	info := map[string]string{
+		MDBuildCommit: firstNonEmpty(b.Commit, BuildCommit),
+		MDBuildBranch: firstNonEmpty(b.Branch, BuildBranch),
+		MDBuildTime:   firstNonEmpty(b.Time, BuildTime),
+	}
+	md := make(map[string]string, len(info))
+	for k, v := range info {
+		if v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// chassisVersionMetadata returns the version of chassis sdk,
+// it is written unless registrator.disableChassisVersion is true
+func chassisVersionMetadata() map[string]string {
+	if !config.GetRegistratorChassisVersion() {
+		return nil
+	}
+	return map[string]string{MDChassisVersion: metadata.SdkVersion}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
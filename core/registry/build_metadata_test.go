@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	BuildCommit, BuildBranch, BuildTime = "1a2b3c", "master", "2018-09-01T10:00:00Z"
+	defer func() { BuildCommit, BuildBranch, BuildTime = "", "", "" }()
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDBuildCommit, "disabled by default")
+
+	b := &config.MicroserviceDefinition.ServiceDescription.Instance.Build
+	b.Enabled = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md := r.instances[1].Metadata
+	assert.Equal(t, "1a2b3c", md[MDBuildCommit])
+	assert.Equal(t, "master", md[MDBuildBranch])
+	assert.Equal(t, "2018-09-01T10:00:00Z", md[MDBuildTime])
+
+	b.Branch = "release-1.0"
+	BuildTime = ""
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md = r.instances[2].Metadata
+	assert.Equal(t, "release-1.0", md[MDBuildBranch], "config takes precedence")
+	assert.Equal(t, "1a2b3c", md[MDBuildCommit])
+	assert.NotContains(t, md, MDBuildTime)
+}
+
+func TestChassisVersionMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, metadata.SdkVersion, r.instances[0].Metadata[chassisKey(MDChassisVersion)])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DisableChassisVersion = true
+	defer func() { config.GlobalDefinition.Cse.Service.Registry.Registrator.DisableChassisVersion = false }()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[1].Metadata, chassisKey(MDChassisVersion))
+}
@@ -44,6 +44,33 @@ func enableRegistryCache() {
 	ProvidersMicroServiceCache = initCache()
 }
 
+// selfInstanceIDs returns the instance ids of self micro-service sid in SelfInstancesCache,
+// an entry of wrong type is reset with a warning instead of being dropped silently
+func selfInstanceIDs(sid string) []string {
+	value, ok := SelfInstancesCache.Get(sid)
+	if !ok {
+		return nil
+	}
+	ids, ok := value.([]string)
+	if !ok {
+		lager.Logger.Warnf("SelfInstancesCache entry of [%s] is %T instead of []string, reset it", sid, value)
+		SelfInstancesCache.Set(sid, []string{}, 0)
+		return nil
+	}
+	return ids
+}
+
+// addSelfInstanceID adds instance id iid of self micro-service sid to SelfInstancesCache
+func addSelfInstanceID(sid, iid string) {
+	ids := selfInstanceIDs(sid)
+	for _, id := range ids {
+		if id == iid {
+			return
+		}
+	}
+	SelfInstancesCache.Set(sid, append(ids, iid), 0)
+}
+
 // CacheIndex is a unified local instances cache manager
 type CacheIndex interface {
 	Get(service string, tags map[string]string) ([]*MicroServiceInstance, bool)
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"sort"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// Pinger is implemented by registrators which are able to check the registry is reachable
+type Pinger interface {
+	Ping() error
+}
+
+// SchemaBatchAdder is implemented by registrators which are able to upload schemas in one call,
+// schemas is keyed by schema id
+type SchemaBatchAdder interface {
+	AddSchemasBatch(microServiceID string, schemas map[string]string) error
+}
+
+// InstancePropertiesUpdater is implemented by registrators which are able to update properties of an instance,
+// properties are the whole metadata of the instance, keys absent in them are removed,
+// Registrator still declares it for compatibility, unable ones return ErrPropertiesUnsupported
+type InstancePropertiesUpdater interface {
+	UpdateMicroServiceInstanceProperties(microServiceID, microServiceInstanceID string, properties map[string]string) error
+}
+
+// optional capabilities of registrator, each is implemented by an optional interface
+const (
+	CapabilityPing             = "Ping"
+	CapabilityAddSchemasBatch  = "AddSchemasBatch"
+	CapabilityDeleteSchema     = "DeleteSchema"
+	CapabilityIdempotency      = "Idempotency"
+	CapabilityRouteRules       = "RouteRules"
+	CapabilityUpdateInstance   = "UpdateInstance"
+	CapabilityUpdateEndpoints  = "UpdateEndpoints"
+	CapabilityInstanceFirst    = "InstanceFirst"
+	CapabilityExpireInstance   = "ExpireInstance"
+	CapabilityUpdateProperties = "UpdateProperties"
+	CapabilityContext          = "Context"
+)
+
+// capabilityChecks tell whether a registrator implements the interface of each capability
+var capabilityChecks = map[string]func(Registrator) bool{
+	CapabilityPing:             func(reg Registrator) bool { _, ok := reg.(Pinger); return ok },
+	CapabilityAddSchemasBatch:  func(reg Registrator) bool { _, ok := reg.(SchemaBatchAdder); return ok },
+	CapabilityDeleteSchema:     func(reg Registrator) bool { _, ok := reg.(SchemaDeleter); return ok },
+	CapabilityIdempotency:      func(reg Registrator) bool { _, ok := reg.(IdempotentRegistrator); return ok },
+	CapabilityRouteRules:       func(reg Registrator) bool { _, ok := reg.(RouteRulePublisher); return ok },
+	CapabilityUpdateInstance:   func(reg Registrator) bool { _, ok := reg.(InstanceUpdater); return ok },
+	CapabilityUpdateEndpoints:  func(reg Registrator) bool { _, ok := reg.(EndpointsUpdater); return ok },
+	CapabilityInstanceFirst:    func(reg Registrator) bool { _, ok := reg.(InstanceFirstRegistrator); return ok },
+	CapabilityExpireInstance:   func(reg Registrator) bool { _, ok := reg.(InstanceExpirer); return ok },
+	CapabilityUpdateProperties: func(reg Registrator) bool { _, ok := reg.(InstancePropertiesUpdater); return ok },
+	CapabilityContext:          func(reg Registrator) bool { _, ok := reg.(ContextRegistrator); return ok },
+}
+
+// Capabilities returns the sorted optional capabilities reg supports
+func Capabilities(reg Registrator) []string {
+	var caps []string
+	for c, check := range capabilityChecks {
+		if check(reg) {
+			caps = append(caps, c)
+		}
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// supported logs the capability in debug level if it is not supported, so that the caller falls back
+func supported(ok bool, capability string) bool {
+	if !ok {
+		lager.Logger.Debugf("Registrator does not support %s, fall back", capability)
+	}
+	return ok
+}
+
+func asPinger(reg Registrator) (Pinger, bool) {
+	p, ok := reg.(Pinger)
+	return p, supported(ok, CapabilityPing)
+}
+
+func asSchemaBatchAdder(reg Registrator) (SchemaBatchAdder, bool) {
+	a, ok := reg.(SchemaBatchAdder)
+	return a, supported(ok, CapabilityAddSchemasBatch)
+}
+
+func asSchemaDeleter(reg Registrator) (SchemaDeleter, bool) {
+	d, ok := reg.(SchemaDeleter)
+	return d, supported(ok, CapabilityDeleteSchema)
+}
+
+func asIdempotentRegistrator(reg Registrator) (IdempotentRegistrator, bool) {
+	ir, ok := reg.(IdempotentRegistrator)
+	return ir, supported(ok, CapabilityIdempotency)
+}
+
+func asRouteRulePublisher(reg Registrator) (RouteRulePublisher, bool) {
+	p, ok := reg.(RouteRulePublisher)
+	return p, supported(ok, CapabilityRouteRules)
+}
+
+func asInstanceUpdater(reg Registrator) (InstanceUpdater, bool) {
+	u, ok := reg.(InstanceUpdater)
+	return u, supported(ok, CapabilityUpdateInstance)
+}
+
+func asEndpointsUpdater(reg Registrator) (EndpointsUpdater, bool) {
+	u, ok := reg.(EndpointsUpdater)
+	return u, supported(ok, CapabilityUpdateEndpoints)
+}
+
+func asInstanceFirstRegistrator(reg Registrator) (InstanceFirstRegistrator, bool) {
+	f, ok := reg.(InstanceFirstRegistrator)
+	return f, supported(ok, CapabilityInstanceFirst)
+}
+
+func asInstanceExpirer(reg Registrator) (InstanceExpirer, bool) {
+	e, ok := reg.(InstanceExpirer)
+	return e, supported(ok, CapabilityExpireInstance)
+}
+
+func asInstancePropertiesUpdater(reg Registrator) (InstancePropertiesUpdater, bool) {
+	u, ok := reg.(InstancePropertiesUpdater)
+	return u, supported(ok, CapabilityUpdateProperties)
+}
+
+func asContextRegistrator(reg Registrator) (ContextRegistrator, bool) {
+	c, ok := reg.(ContextRegistrator)
+	return c, supported(ok, CapabilityContext)
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchRegistrator pings and uploads schemas in one call
+type batchRegistrator struct {
+	*fakeRegistrator
+	batches []map[string]string
+	pingErr error
+}
+
+func (b *batchRegistrator) Ping() error { return b.pingErr }
+
+func (b *batchRegistrator) AddSchemasBatch(sid string, schemas map[string]string) error {
+	b.batches = append(b.batches, schemas)
+	return b.schemaErr
+}
+
+// propertylessRegistrator implements only the methods of Registrator, it is not able to update instance properties
+type propertylessRegistrator struct {
+	f *fakeRegistrator
+}
+
+func (p *propertylessRegistrator) Close() error { return nil }
+func (p *propertylessRegistrator) RegisterService(ms *MicroService) (string, error) {
+	return p.f.RegisterService(ms)
+}
+func (p *propertylessRegistrator) RegisterServiceInstance(sid string, ins *MicroServiceInstance) (string, error) {
+	return p.f.RegisterServiceInstance(sid, ins)
+}
+func (p *propertylessRegistrator) RegisterServiceAndInstance(ms *MicroService, ins *MicroServiceInstance) (string, string, error) {
+	return p.f.RegisterServiceAndInstance(ms, ins)
+}
+func (p *propertylessRegistrator) Heartbeat(sid, iid string) (bool, error) {
+	return p.f.Heartbeat(sid, iid)
+}
+func (p *propertylessRegistrator) AddDependencies(dep *MicroServiceDependency) error {
+	return p.f.AddDependencies(dep)
+}
+func (p *propertylessRegistrator) UnRegisterMicroServiceInstance(sid, iid string) error {
+	return p.f.UnRegisterMicroServiceInstance(sid, iid)
+}
+func (p *propertylessRegistrator) UpdateMicroServiceInstanceStatus(sid, iid, status string) error {
+	return p.f.UpdateMicroServiceInstanceStatus(sid, iid, status)
+}
+func (p *propertylessRegistrator) UpdateMicroServiceProperties(sid string, properties map[string]string) error {
+	return p.f.UpdateMicroServiceProperties(sid, properties)
+}
+func (p *propertylessRegistrator) UpdateMicroServiceInstanceProperties(sid, iid string, properties map[string]string) error {
+	return ErrPropertiesUnsupported
+}
+func (p *propertylessRegistrator) AddSchemas(sid, schemaName, schemaInfo string) error {
+	return p.f.AddSchemas(sid, schemaName, schemaInfo)
+}
+
+func TestCapabilities(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.Equal(t, []string{CapabilityUpdateProperties}, Capabilities(r))
+	assert.Equal(t, []string{CapabilityUpdateProperties}, Capabilities(&propertylessRegistrator{r}), "declared by Registrator")
+	assert.Empty(t, Capabilities(nil))
+	assert.Equal(t, []string{CapabilityAddSchemasBatch, CapabilityPing, CapabilityUpdateProperties}, Capabilities(&batchRegistrator{fakeRegistrator: r}))
+	assert.Equal(t, []string{CapabilityDeleteSchema, CapabilityUpdateProperties}, Capabilities(&fakeSchemaDeleter{fakeRegistrator: r}))
+}
+
+func TestMinimalRegistratorFallbacks(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	registeredSchemas, registeredSchemasSID = nil, ""
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DeleteStaleSchemas = true
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, RegisterMicroservice())
+	loadTestSchemas(t, "s1")
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, waitSchemaStatus(t, SchemaReady))
+	assert.Contains(t, r.schemas, "s1", "schemas are uploaded one by one")
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, UpdateInstance(runtime.StatusOutOfService, map[string]string{"release": "canary"}))
+	assert.Equal(t, "canary", r.properties["release"])
+	assert.NoError(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9:8080"}))
+	assert.NoError(t, registryReachable(), "falls back to listing micro-services")
+}
+
+func TestUnsupportedInstanceProperties(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	DefaultRegistrator = &propertylessRegistrator{r}
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"release": "stable"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances(), "skipped by default")
+	assert.Equal(t, 1, len(r.instances))
+	assert.Equal(t, 0, r.propertyUpdates)
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"release": "canary"}))
+	assert.NoError(t, UpdateInstance(runtime.StatusOutOfService, nil))
+	assert.Equal(t, []string{runtime.StatusOutOfService}, r.status)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.UnsupportedProperties = UnsupportedPropertiesFail
+	assert.Equal(t, ErrPropertiesUnsupported, UpdateInstanceMetadata(map[string]string{"release": "canary"}))
+	assert.Equal(t, ErrPropertiesUnsupported, RegisterMicroserviceInstances())
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.UnsupportedProperties = "ignore"
+	assert.Error(t, UpdateInstanceMetadata(map[string]string{"release": "canary"}))
+	assert.Equal(t, 0, r.propertyUpdates)
+}
+
+func TestOptionalCapabilities(t *testing.T) {
+	r, d := initBootstrapEnv()
+	b := &batchRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = b
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, NewRegistrationRunner(b, d).RegisterMicroservice())
+	assert.NoError(t, waitSchemaStatus(t, SchemaReady))
+	assert.Equal(t, 1, len(b.batches))
+	assert.Contains(t, b.batches[0], "s1")
+	assert.Contains(t, b.batches[0], "s2")
+	assert.Empty(t, r.schemas)
+
+	r.schemaErr = errors.New("too large")
+	assert.NoError(t, NewRegistrationRunner(b, d).RegisterMicroservice())
+	assert.Error(t, waitSchemaStatus(t, SchemaFailed))
+
+	assert.NoError(t, registryReachable())
+	b.pingErr = errors.New("unreachable")
+	assert.Equal(t, b.pingErr, registryReachable())
+}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// checkpoint records the last registered ids of self micro-service and instance,
+// so that a restarted process can clean up what its last run left in registry
+type checkpoint struct {
+	ServiceID  string `json:"serviceId"`
+	InstanceID string `json:"instanceId,omitempty"`
+	// Stale are instances left by earlier runs which are not unregistered yet
+	Stale []staleInstance `json:"stale,omitempty"`
+}
+
+// staleInstance is an instance registered by an earlier run
+type staleInstance struct {
+	ServiceID  string `json:"serviceId"`
+	InstanceID string `json:"instanceId"`
+}
+
+// previousCheckpoint is the checkpoint left by last run, it is loaded before the first write
+var previousCheckpoint *checkpoint
+var checkpointLoaded bool
+
+// staleInstances are carried forward in every checkpoint write until they are unregistered,
+// so that a process crashing again before registering its instance does not lose them
+var staleInstances []staleInstance
+var checkpointMu sync.Mutex
+
+// loadCheckpoint loads the checkpoint left by last run if it has not been loaded, checkpointMu must be held
+func loadCheckpoint(path string) {
+	if checkpointLoaded {
+		return
+	}
+	checkpointLoaded = true
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			lager.Logger.Warnf("Read registration checkpoint [%s] failed: %s", path, err)
+		}
+		return
+	}
+	cp := &checkpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		lager.Logger.Warnf("Registration checkpoint [%s] is broken: %s", path, err)
+		return
+	}
+	previousCheckpoint = cp
+	staleInstances = append(staleInstances, cp.Stale...)
+	if cp.InstanceID != "" {
+		staleInstances = append(staleInstances, staleInstance{ServiceID: cp.ServiceID, InstanceID: cp.InstanceID})
+	}
+}
+
+// saveCheckpoint writes cp with the stale instances to the checkpoint file atomically,
+// it only takes effect when checkpoint is configured
+func saveCheckpoint(cp checkpoint) {
+	path := config.GetRegistratorCheckpoint()
+	if path == "" {
+		return
+	}
+	checkpointMu.Lock()
+	defer checkpointMu.Unlock()
+	loadCheckpoint(path)
+	for _, s := range staleInstances {
+		if s.InstanceID != cp.InstanceID {
+			cp.Stale = append(cp.Stale, s)
+		}
+	}
+	if err := writeFileAtomic(path, cp); err != nil {
+		lager.Logger.Warnf("Write registration checkpoint [%s] failed: %s", path, err)
+	}
+}
+
+// writeFileAtomic writes v as json to a temp file in the dir of path, then renames it to path
+func writeFileAtomic(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// cleanPreviousInstance unregisters the instances left by earlier runs except the current one iid,
+// those failed to be unregistered are kept in checkpoint and retried by the next registration,
+// a service left by last run under another id can not be deleted, it is only logged
+func (r *RegistrationRunner) cleanPreviousInstance(sid, iid string) {
+	path := config.GetRegistratorCheckpoint()
+	if path == "" {
+		return
+	}
+	checkpointMu.Lock()
+	loadCheckpoint(path)
+	prev, stale := previousCheckpoint, staleInstances
+	previousCheckpoint = nil
+	checkpointMu.Unlock()
+	if prev != nil && prev.ServiceID != sid {
+		lager.Logger.Warnf("Last run registered as service [%s], now it is [%s]", prev.ServiceID, sid)
+	}
+	var failed []staleInstance
+	for _, s := range stale {
+		if s.InstanceID == iid {
+			continue
+		}
+		if err := r.Registrator.UnRegisterMicroServiceInstance(s.ServiceID, s.InstanceID); err != nil {
+			lager.Logger.Warnf("Unregister instance [%s/%s] left by earlier run failed: %s", s.ServiceID, s.InstanceID, err)
+			failed = append(failed, s)
+			continue
+		}
+		lager.Logger.Infof("Unregister instance [%s/%s] left by earlier run success", s.ServiceID, s.InstanceID)
+	}
+	checkpointMu.Lock()
+	staleInstances = failed
+	checkpointMu.Unlock()
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// restartCheckpoint simulates a restarted process which has not read the checkpoint
+func restartCheckpoint() {
+	previousCheckpoint = nil
+	checkpointLoaded = false
+	staleInstances = nil
+}
+
+// failingUnregistrator fails to unregister instances until err is cleared
+type failingUnregistrator struct {
+	*fakeRegistrator
+	err error
+}
+
+func (f *failingUnregistrator) UnRegisterMicroServiceInstance(sid, iid string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.fakeRegistrator.UnRegisterMicroServiceInstance(sid, iid)
+}
+
+func readCheckpoint(t *testing.T, path string) checkpoint {
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	cp := checkpoint{}
+	assert.NoError(t, json.Unmarshal(b, &cp))
+	return cp
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registration.json")
+	defer restartCheckpoint()
+
+	r, _ := initBootstrapEnv()
+	restartCheckpoint()
+	assert.NoError(t, RegisterMicroservice())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "no checkpoint by default")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Checkpoint = path
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, checkpoint{ServiceID: "sid"}, readCheckpoint(t, path))
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, checkpoint{ServiceID: "sid", InstanceID: "iid"}, readCheckpoint(t, path))
+	assert.Empty(t, r.unregistered)
+
+	// restart, the instance left by last run is unregistered
+	r, _ = initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Checkpoint = path
+	restartCheckpoint()
+	r.iid = "newIid"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, []string{"sid/iid"}, r.unregistered)
+	assert.Equal(t, checkpoint{ServiceID: "sid", InstanceID: "newIid"}, readCheckpoint(t, path))
+
+	// only last run is cleaned
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.unregistered))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(files), "temp files are renamed")
+}
+
+func TestCheckpointStaleInstances(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registration.json")
+	defer restartCheckpoint()
+
+	restart := func(iid string) *failingUnregistrator {
+		r, _ := initBootstrapEnv()
+		config.GlobalDefinition.Cse.Service.Registry.Registrator.Checkpoint = path
+		restartCheckpoint()
+		r.iid = iid
+		f := &failingUnregistrator{fakeRegistrator: r}
+		DefaultRegistrator = f
+		return f
+	}
+	restart("iid")
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	// crashes before registering instance, the old instance is kept
+	restart("iid2")
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, checkpoint{ServiceID: "sid", Stale: []staleInstance{{"sid", "iid"}}}, readCheckpoint(t, path))
+
+	// unregister fails, the old instance is kept for next run
+	f := restart("iid2")
+	f.err = errors.New("unavailable")
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Empty(t, f.unregistered)
+	assert.Equal(t, checkpoint{ServiceID: "sid", InstanceID: "iid2", Stale: []staleInstance{{"sid", "iid"}}}, readCheckpoint(t, path))
+
+	// retried in the same process
+	f.err = nil
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, []string{"sid/iid"}, f.unregistered)
+	assert.Equal(t, checkpoint{ServiceID: "sid", InstanceID: "iid2"}, readCheckpoint(t, path))
+
+	// both earlier instances are cleaned by next run
+	f = restart("iid3")
+	f.err = errors.New("unavailable")
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	restartCheckpoint()
+	f.iid = "iid4"
+	f.err = nil
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.ElementsMatch(t, []string{"sid/iid2", "sid/iid3"}, f.unregistered)
+	assert.Equal(t, checkpoint{ServiceID: "sid", InstanceID: "iid4"}, readCheckpoint(t, path))
+}
@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// ErrRegistrationCircuitOpen means registrator calls are short-circuited after consecutive failures
+var ErrRegistrationCircuitOpen = errors.New("registration circuit breaker is open, registrator call is short-circuited")
+
+// states of registration circuit breaker
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// defaultBreakerCooldown is how long the breaker stays open if cooldown is not configured
+const defaultBreakerCooldown = 30 * time.Second
+
+// registrationBreaker guards registrator calls of this process, retries and heartbeats included
+var registrationBreaker = &circuitBreaker{state: BreakerClosed}
+
+// circuitBreaker opens after failureThreshold consecutive failures of cse.service.registry.registrator.circuitBreaker,
+// short-circuits calls for cooldown, then lets one call probe the registry in half-open state,
+// it is disabled if failureThreshold is not positive
+type circuitBreaker struct {
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// call runs f unless the breaker is open, and records its result
+func (b *circuitBreaker) call(f func() error) error {
+	threshold, cooldown := breakerConfig()
+	if threshold <= 0 {
+		return f()
+	}
+	if !b.allow(cooldown) {
+		return ErrRegistrationCircuitOpen
+	}
+	err := f()
+	b.record(err, threshold)
+	return err
+}
+
+func (b *circuitBreaker) allow(cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if nowFunc().Sub(b.openedAt) < cooldown {
+			return false
+		}
+		lager.Logger.Info("Registration circuit breaker is half open, probe registry")
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// only one probe at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *circuitBreaker) record(err error, threshold int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+	if err == nil {
+		if b.state != BreakerClosed {
+			lager.Logger.Info("Registration circuit breaker is closed")
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= threshold {
+		if b.state == BreakerClosed {
+			lager.Logger.Warnf("Registration circuit breaker is open after %d consecutive failures: %s", b.failures, err)
+		}
+		b.state = BreakerOpen
+		b.openedAt = nowFunc()
+	}
+}
+
+// BreakerState returns the state of registration circuit breaker
+func BreakerState() string {
+	registrationBreaker.mu.Lock()
+	defer registrationBreaker.mu.Unlock()
+	return registrationBreaker.state
+}
+
+// breakerConfig returns failure threshold and cooldown of registration circuit breaker
+func breakerConfig() (int, time.Duration) {
+	c := config.GetRegistratorCircuitBreaker()
+	if c.Cooldown == "" {
+		return c.FailureThreshold, defaultBreakerCooldown
+	}
+	d, err := time.ParseDuration(c.Cooldown)
+	if err != nil || d <= 0 {
+		lager.Logger.Warnf("circuit breaker cooldown is invalid [%s], %s is used", c.Cooldown, defaultBreakerCooldown)
+		return c.FailureThreshold, defaultBreakerCooldown
+	}
+	return c.FailureThreshold, d
+}
@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationCircuitBreaker(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	now := time.Now()
+	nowFunc = func() time.Time { return now }
+	defer func() {
+		nowFunc = time.Now
+		registrationBreaker = &circuitBreaker{state: BreakerClosed}
+	}()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.CircuitBreaker = model.RegistratorBreaker{
+		FailureThreshold: 2,
+		Cooldown:         "10s",
+	}
+	r.err = errors.New("registry unreachable")
+
+	assert.Equal(t, r.err, RegisterMicroservice())
+	assert.Equal(t, BreakerClosed, BreakerState())
+	assert.Equal(t, r.err, RegisterMicroservice())
+	assert.Equal(t, BreakerOpen, BreakerState())
+	assert.Equal(t, 2, len(r.services))
+
+	// short-circuited during cooldown
+	assert.Equal(t, ErrRegistrationCircuitOpen, RegisterMicroservice())
+	assert.Equal(t, 2, len(r.services))
+
+	// failed probe opens it again
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, r.err, RegisterMicroservice())
+	assert.Equal(t, BreakerOpen, BreakerState())
+	assert.Equal(t, 3, len(r.services))
+	assert.Equal(t, ErrRegistrationCircuitOpen, RegisterMicroservice())
+
+	// successful probe closes it
+	now = now.Add(10 * time.Second)
+	r.err = nil
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, BreakerClosed, BreakerState())
+	assert.Equal(t, 4, len(r.services))
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	b := &circuitBreaker{state: BreakerOpen, openedAt: nowFunc().Add(-time.Minute)}
+	assert.True(t, b.allow(time.Second))
+	assert.Equal(t, BreakerHalfOpen, b.state)
+	assert.False(t, b.allow(time.Second), "only one probe at a time")
+	b.record(nil, 1)
+	assert.True(t, b.allow(time.Second))
+
+	// disabled by default
+	initBootstrapEnv()
+	assert.NoError(t, (&circuitBreaker{state: BreakerOpen, openedAt: nowFunc()}).call(func() error { return nil }))
+}
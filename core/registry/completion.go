@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+var completionHandlers []func(err error)
+var completed bool
+var completionErr error
+var completionMu sync.Mutex
+
+// OnRegistrationComplete registers f to be called once registration of both micro-service
+// and instance finishes, err is nil on success, f is called at once if registration already finished,
+// in manual register mode instance is registered by application, so f is only called on failure
+func OnRegistrationComplete(f func(err error)) {
+	completionMu.Lock()
+	if !completed {
+		completionHandlers = append(completionHandlers, f)
+		completionMu.Unlock()
+		return
+	}
+	err := completionErr
+	completionMu.Unlock()
+	callCompletionHandler(f, err)
+}
+
+// registrationCompleted records the final result of registration and calls handlers,
+// only the first result takes effect
+func registrationCompleted(err error) {
+	completionMu.Lock()
+	if completed {
+		completionMu.Unlock()
+		return
+	}
+	completed = true
+	completionErr = err
+	handlers := completionHandlers
+	completionHandlers = nil
+	completionMu.Unlock()
+	for _, f := range handlers {
+		callCompletionHandler(f, err)
+	}
+}
+
+func callCompletionHandler(f func(err error), err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			lager.Logger.Errorf("registration complete handler panic: %v", r)
+		}
+	}()
+	f(err)
+}
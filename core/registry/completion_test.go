@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func resetCompletion() {
+	completionMu.Lock()
+	completed, completionErr, completionHandlers = false, nil, nil
+	completionMu.Unlock()
+}
+
+func TestOnRegistrationComplete(t *testing.T) {
+	initBootstrapEnv()
+	resetCompletion()
+	defer resetCompletion()
+
+	var results []error
+	OnRegistrationComplete(func(err error) { results = append(results, err) })
+	OnRegistrationComplete(func(err error) { panic("boom") })
+	OnRegistrationComplete(func(err error) { results = append(results, err) })
+	assert.NoError(t, RegisterMicroservice())
+	assert.Empty(t, results, "instance is not registered yet")
+
+	assert.NoError(t, DoRegister())
+	assert.Equal(t, []error{nil, nil}, results)
+
+	// called only once
+	assert.NoError(t, DoRegister())
+	assert.Equal(t, 2, len(results))
+	// registered after completion
+	OnRegistrationComplete(func(err error) { results = append(results, err) })
+	assert.Equal(t, 3, len(results))
+	assert.Nil(t, results[2])
+}
+
+func TestOnRegistrationCompleteFailure(t *testing.T) {
+	initBootstrapEnv()
+	resetCompletion()
+	defer resetCompletion()
+
+	var result error
+	OnRegistrationComplete(func(err error) { result = err })
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.AutoRegister = "unknown"
+	assert.Error(t, DoRegister())
+	assert.Error(t, result)
+
+	resetCompletion()
+	errFail := errors.New("registry unavailable")
+	registrationCompleted(errFail)
+	OnRegistrationComplete(func(err error) { result = err })
+	assert.Equal(t, errFail, result)
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/hashicorp/go-version"
+)
+
+// validVersionRule checks rule is a provider version constraint registry understands:
+// latest, an exact version like 1.0.0, a minimum version like 1.0.0+, or a range like 1.0.0-2.0.0
+func validVersionRule(rule string) error {
+	switch {
+	case rule == common.LatestVersion:
+		return nil
+	case strings.HasSuffix(rule, "+"):
+		if !versionRegex.MatchString(strings.TrimSuffix(rule, "+")) {
+			return fmt.Errorf("version rule [%s] is invalid", rule)
+		}
+		return nil
+	case strings.Contains(rule, "-"):
+		bounds := strings.SplitN(rule, "-", 2)
+		if !versionRegex.MatchString(bounds[0]) || !versionRegex.MatchString(bounds[1]) {
+			return fmt.Errorf("version rule [%s] is invalid", rule)
+		}
+		low, _ := version.NewVersion(bounds[0])
+		high, _ := version.NewVersion(bounds[1])
+		if low.GreaterThan(high) {
+			return fmt.Errorf("version range [%s] is empty", rule)
+		}
+		return nil
+	case versionRegex.MatchString(rule):
+		return nil
+	}
+	return fmt.Errorf("version rule [%s] is invalid, must be latest, x.y.z, x.y.z+ or x.y.z-x.y.z", rule)
+}
+
+// addDependencies sends the declared providers of self micro-service sid to registry, so that
+// registry knows which provider versions it depends on
+func (r *RegistrationRunner) addDependencies(sid string, dep *MicroServiceDependency) error {
+	if dep == nil || len(dep.Providers) == 0 {
+		return nil
+	}
+	dep.Consumer.ServiceID = sid
+	if err := callWithTimeout(OpRegisterService, func() error {
+		return r.Registrator.AddDependencies(dep)
+	}); err != nil {
+		lager.Logger.Errorf("Add dependencies of [%s] failed: %s", sid, err)
+		return err
+	}
+	lager.Logger.Infof("Add %d dependencies of [%s] success", len(dep.Providers), sid)
+	return nil
+}
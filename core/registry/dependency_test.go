@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// dependencyRegistrator records the dependencies sent to registry
+type dependencyRegistrator struct {
+	*fakeRegistrator
+	deps   []*MicroServiceDependency
+	depErr error
+}
+
+func (d *dependencyRegistrator) AddDependencies(dep *MicroServiceDependency) error {
+	d.deps = append(d.deps, dep)
+	return d.depErr
+}
+
+func TestAddDependencies(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	dr := &dependencyRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = dr
+	assert.NoError(t, RegisterMicroservice())
+	assert.Empty(t, dr.deps, "nothing to add")
+
+	config.GlobalDefinition.Cse.References = map[string]model.ReferencesStruct{
+		"Server": {Version: "1.0.0"},
+		"Legacy": {},
+	}
+	config.MicroserviceDefinition.ServiceDescription.Dependencies = []model.DependencyStruct{
+		{Name: "Server", Version: "1.2.0+"},
+		{Name: "Billing", AppID: "finance", Version: "2.0.0-3.0.0"},
+	}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 1, len(dr.deps))
+	dep := dr.deps[0]
+	assert.Equal(t, "sid", dep.Consumer.ServiceID)
+	var providers []string
+	for _, p := range dep.Providers {
+		providers = append(providers, p.AppID+"/"+p.ServiceName+":"+p.Version)
+	}
+	sort.Strings(providers)
+	assert.Equal(t, []string{"default/Legacy:latest", "default/Server:1.2.0+", "finance/Billing:2.0.0-3.0.0"}, providers)
+
+	dr.depErr = errors.New("registry rejected")
+	assert.Equal(t, dr.depErr, RegisterMicroservice())
+	dr.depErr = nil
+
+	config.MicroserviceDefinition.ServiceDescription.Dependencies = []model.DependencyStruct{
+		{Name: "Server", Version: "1.2.0++"},
+		{Name: "Billing", Version: "3.0.0-2.0.0"},
+		{Name: "Billing", Version: "~1.0"},
+	}
+	config.GlobalDefinition.Cse.References["Legacy"] = model.ReferencesStruct{Version: "v2"}
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.dependencies[0].version: version rule [1.2.0++] is invalid")
+	assert.Contains(t, err.Error(), "service_description.dependencies[1].version: version range [3.0.0-2.0.0] is empty")
+	assert.Contains(t, err.Error(), "service_description.dependencies[2].name: duplicated provider [Billing]")
+	assert.Contains(t, err.Error(), "service_description.dependencies[2].version")
+	assert.Contains(t, err.Error(), "cse.references.Legacy.version")
+	assert.Equal(t, 2, len(dr.deps), "invalid dependencies are not sent")
+}
+
+func TestValidVersionRule(t *testing.T) {
+	for _, rule := range []string{"latest", "1.0.0", "1.0", "1.0.0+", "0+", "1.0.0-1.0.0", "1.0.0-2.1"} {
+		assert.NoError(t, validVersionRule(rule), rule)
+	}
+	for _, rule := range []string{"", "Latest", "+", "1.0.0-", "-1.0.0", "1.0.0-2.0.0-3.0.0", ">=1.0.0"} {
+		assert.Error(t, validVersionRule(rule), rule)
+	}
+}
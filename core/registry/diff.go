@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// ErrServiceNotRegistered means self micro-service has not been registered yet
+var ErrServiceNotRegistered = errors.New("self micro-service is not registered")
+
+// FieldDiff is a value which differs between local definition and registry,
+// an empty side means the value is absent there
+type FieldDiff struct {
+	Local    string
+	Registry string
+}
+
+// ServiceDiff describes how the micro-service held by registry diverges from local definition
+type ServiceDiff struct {
+	Level      *FieldDiff
+	Metadata   map[string]FieldDiff
+	Properties map[string]FieldDiff
+	// MissingSchemas are defined locally but absent in registry
+	MissingSchemas []string
+	// StaleSchemas are in registry but no longer defined locally
+	StaleSchemas []string
+}
+
+// Empty returns whether registry holds the same micro-service as local definition
+func (d *ServiceDiff) Empty() bool {
+	return d.Level == nil && len(d.Metadata) == 0 && len(d.Properties) == 0 &&
+		len(d.MissingSchemas) == 0 && len(d.StaleSchemas) == 0
+}
+
+// DiffRegisteredService compares local definition of self micro-service with the one in registry
+func DiffRegisteredService() (ServiceDiff, error) {
+	return defaultRunner().DiffRegisteredService()
+}
+
+// DiffRegisteredService compares local definition of self micro-service with the one in registry
+func (r *RegistrationRunner) DiffRegisteredService() (ServiceDiff, error) {
+	sid, _ := selfIDs()
+	if sid == "" {
+		return ServiceDiff{}, ErrServiceNotRegistered
+	}
+	remote, err := r.Discovery.GetMicroService(sid)
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+	if remote == nil {
+		return ServiceDiff{}, ErrServiceNotRegistered
+	}
+	// remote metadata is decoded by discovery,
+	// compare with what registration sends, so truncated schemas and signature are not reported as drift
+	local, err := sealedMicroService()
+	if err != nil {
+		return ServiceDiff{}, err
+	}
+	if local.Metadata, err = DecodeMetadata(local.Metadata); err != nil {
+		return ServiceDiff{}, err
+	}
+	return diffService(local, config.MicroserviceDefinition.ServiceDescription.Properties, remote), nil
+}
+
+// diffService compares local micro-service and its declared properties with remote,
+// registry keeps metadata and properties in one set, so a remote key is only reported
+// when it is neither local metadata nor a declared property
+func diffService(local *MicroService, properties map[string]string, remote *MicroService) ServiceDiff {
+	d := ServiceDiff{
+		Metadata:   make(map[string]FieldDiff),
+		Properties: make(map[string]FieldDiff),
+	}
+	if local.Level != remote.Level {
+		d.Level = &FieldDiff{Local: local.Level, Registry: remote.Level}
+	}
+	for k, v := range local.Metadata {
+		if k == chassisKey(MDSignature) {
+			// signature covers the service id which is assigned after registration, signed fields are compared one by one
+			continue
+		}
+		if rv, ok := remote.Metadata[k]; !ok || rv != v {
+			d.Metadata[k] = FieldDiff{Local: v, Registry: rv}
+		}
+	}
+	for k, v := range properties {
+		if _, ok := local.Metadata[k]; ok || k == MDAllowCrossApp {
+			// allowCrossApp is registered as metadata when scope is full
+			continue
+		}
+		if rv, ok := remote.Metadata[k]; !ok || rv != v {
+			d.Properties[k] = FieldDiff{Local: v, Registry: rv}
+		}
+	}
+	for k, rv := range remote.Metadata {
+		_, inMetadata := local.Metadata[k]
+		_, inProperties := properties[k]
+		if !inMetadata && !inProperties {
+			d.Metadata[k] = FieldDiff{Registry: rv}
+		}
+	}
+	d.MissingSchemas = subtract(local.Schemas, remote.Schemas)
+	d.StaleSchemas = subtract(remote.Schemas, local.Schemas)
+	return d
+}
+
+// subtract returns sorted elements of a which are not in b
+func subtract(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, s := range b {
+		set[s] = true
+	}
+	var r []string
+	for _, s := range a {
+		if !set[s] {
+			r = append(r, s)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRegisteredService(t *testing.T) {
+	r, d := initBootstrapEnv()
+	_, err := DiffRegisteredService()
+	assert.Equal(t, ErrServiceNotRegistered, err)
+
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	config.MicroserviceDefinition.ServiceDescription.Properties = map[string]string{"owner": "team-a"}
+	assert.NoError(t, RegisterMicroservice())
+	d.services["sid"] = &MicroService{
+		ServiceID: "sid",
+		Level:     r.services[0].Level,
+		Schemas:   r.services[0].Schemas,
+		Metadata:  map[string]string{MDAllowCrossApp: common.TRUE, "owner": "team-a"},
+	}
+	diff, err := DiffRegisteredService()
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	d.services["sid"] = &MicroService{
+		ServiceID: "sid",
+		Level:     "FRONT",
+		Schemas:   append([]string{"old"}, r.services[0].Schemas...),
+		Metadata:  map[string]string{"owner": "team-b", "legacy": "1"},
+	}
+	config.MicroserviceDefinition.ServiceDescription.Properties["zone"] = "z1"
+	diff, err = DiffRegisteredService()
+	assert.NoError(t, err)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, &FieldDiff{Local: common.DefaultLevel, Registry: "FRONT"}, diff.Level)
+	assert.Equal(t, FieldDiff{Local: common.TRUE}, diff.Metadata[MDAllowCrossApp])
+	assert.Equal(t, FieldDiff{Registry: "1"}, diff.Metadata["legacy"])
+	assert.Equal(t, FieldDiff{Local: "team-a", Registry: "team-b"}, diff.Properties["owner"])
+	assert.Equal(t, FieldDiff{Local: "z1"}, diff.Properties["zone"])
+	assert.Equal(t, []string{"old"}, diff.StaleSchemas)
+	assert.Empty(t, diff.MissingSchemas)
+}
+
+func TestDiffPreparedService(t *testing.T) {
+	r, d := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	registrator := &config.GlobalDefinition.Cse.Service.Registry.Registrator
+	registrator.MetadataEncoding = MetadataEncodingBase64
+	registrator.MaxSchemas = 2
+	registrator.MaxSchemasPolicy = MaxSchemasTruncate
+	loadTestSchemas(t, "s1", "s2", "s3")
+	assert.NoError(t, RegisterMicroservice())
+
+	registered := *r.services[0]
+	// as read back by discovery
+	md, err := DecodeMetadata(registered.Metadata)
+	assert.NoError(t, err)
+	registered.Metadata = md
+	d.services["sid"] = &registered
+	diff, err := DiffRegisteredService()
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty(), "truncated schemas, signature and encoding are not drift: %+v", diff)
+}
@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// sleepFunc waits for the grace period during drain, tests replace it
+var sleepFunc = time.Sleep
+
+// ShutdownGrace returns the declared shutdown grace period of self instance, 0 if it is not declared
+func ShutdownGrace() (time.Duration, error) {
+	s := config.MicroserviceDefinition.ServiceDescription.Instance.ShutdownGrace
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shutdown grace [%s]: %s", s, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("shutdown grace must not be negative, got %s", s)
+	}
+	return d, nil
+}
+
+// DrainInstance puts self instance out of service and waits for grace,
+// so that consumers stop sending new requests before servers stop,
+// a non positive grace means the declared shutdown grace, nothing is done if it is 0
+func DrainInstance(grace time.Duration) error {
+	if grace <= 0 {
+		g, err := ShutdownGrace()
+		if err != nil {
+			return err
+		}
+		grace = g
+	}
+	if grace == 0 {
+		return nil
+	}
+	if err := updateInstanceStatus(runtime.StatusOutOfService); err != nil {
+		return err
+	}
+	lager.Logger.Infof("Instance is out of service, drain for %s", grace)
+	sleepFunc(grace)
+	return nil
+}
+
+// InstanceExpirer is implemented by registrators which are able to remove an instance from discovery immediately,
+// regardless of its ttl
+type InstanceExpirer interface {
+	ExpireMicroServiceInstance(microServiceID, microServiceInstanceID string) error
+}
+
+// ForceExpireSelfInstance puts self instance out of service and removes it from discovery immediately without drain,
+// it is expired if registrator implements InstanceExpirer, otherwise it is unregistered,
+// heartbeat of it is stopped so that it is not registered again
+func ForceExpireSelfInstance() error {
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	if err := updateInstanceStatus(runtime.StatusOutOfService); err != nil {
+		lager.Logger.Warnf("Put instance out of service failed, expire it anyway: %s", err)
+	}
+	DefaultInstanceHeartbeat.Stop()
+	HBService.RemoveTask(sid, iid)
+	var err error
+	if registrationSkipped() {
+		lager.Logger.Debugf("Registration is disabled, instance %s/%s is only expired locally", sid, iid)
+	} else if expirer, ok := asInstanceExpirer(DefaultRegistrator); ok {
+		err = expirer.ExpireMicroServiceInstance(sid, iid)
+	} else {
+		err = DefaultRegistrator.UnRegisterMicroServiceInstance(sid, iid)
+	}
+	if err != nil {
+		lager.Logger.Errorf("Force expire instance %s/%s failed: %s", sid, iid, err)
+		return err
+	}
+	setSelfInstanceID("")
+	removeAdvertisedEndpoints(iid)
+	lager.Logger.Warnf("Instance %s/%s is force expired", sid, iid)
+	return nil
+}
@@ -0,0 +1,89 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownGrace(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDShutdownGrace)
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.ShutdownGrace = "30s"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "30s", r.instances[1].Metadata[MDShutdownGrace])
+
+	for _, s := range []string{"30", "-1s"} {
+		config.MicroserviceDefinition.ServiceDescription.Instance.ShutdownGrace = s
+		assert.Error(t, RegisterMicroserviceInstances(), s)
+	}
+	assert.Equal(t, 2, len(r.instances))
+}
+
+func TestDrainInstance(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	var slept []time.Duration
+	sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleepFunc = time.Sleep }()
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, DrainInstance(0))
+	assert.Empty(t, slept, "nothing to drain without grace")
+	assert.Empty(t, r.status)
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.ShutdownGrace = "30s"
+	assert.NoError(t, DrainInstance(0))
+	assert.Equal(t, []time.Duration{30 * time.Second}, slept)
+	assert.Equal(t, []string{runtime.StatusOutOfService}, r.status)
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+
+	assert.NoError(t, DrainInstance(time.Second))
+	assert.Equal(t, time.Second, slept[1])
+}
+
+// expiringRegistrator removes instances immediately
+type expiringRegistrator struct {
+	*fakeRegistrator
+	expired []string
+}
+
+func (e *expiringRegistrator) ExpireMicroServiceInstance(sid, iid string) error {
+	e.expired = append(e.expired, sid+"/"+iid)
+	return nil
+}
+
+func TestForceExpireSelfInstance(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.Equal(t, ErrInstanceNotRegistered, ForceExpireSelfInstance())
+	e := &expiringRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = e
+	config.MicroserviceDefinition.ServiceDescription.Instance.ShutdownGrace = "30s"
+	var slept time.Duration
+	sleepFunc = func(d time.Duration) { slept += d }
+	defer func() { sleepFunc = time.Sleep }()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	HBService.AddTask("sid", "iid")
+
+	assert.NoError(t, ForceExpireSelfInstance())
+	assert.Equal(t, []string{runtime.StatusOutOfService}, r.status)
+	assert.Equal(t, []string{"sid/iid"}, e.expired)
+	assert.Empty(t, r.unregistered)
+	assert.Equal(t, time.Duration(0), slept, "drain is bypassed")
+	assert.Equal(t, "", runtime.InstanceID)
+	HBService.mux.Lock()
+	assert.NotContains(t, HBService.instances, "sid/iid")
+	HBService.mux.Unlock()
+
+	// unregistered if registrator can not expire instances
+	DefaultRegistrator = r
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, ForceExpireSelfInstance())
+	assert.Equal(t, []string{"sid/iid"}, r.unregistered)
+}
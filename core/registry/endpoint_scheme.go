@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/pkg/util"
+)
+
+// endpointSchemes are the plain and TLS schemes of protocols,
+// other protocols use their names as the scheme
+var endpointSchemes = map[string][2]string{
+	common.ProtocolRest: {"http", "https"},
+	"grpc":              {"grpc", "grpcs"},
+}
+
+// protocolScheme returns the scheme of endpoint ep of protocol name derived from the protocol and TLS config
+func protocolScheme(name, ep string) string {
+	protocol, _, err := util.ParsePortName(strings.TrimSuffix(name, sslEndpointSuffix))
+	if err != nil {
+		protocol = name
+	}
+	schemes, ok := endpointSchemes[protocol]
+	if !ok {
+		return protocol
+	}
+	if endpointTLS(name, ep) {
+		return schemes[1]
+	}
+	return schemes[0]
+}
+
+// schemedEndpoints returns eps with the scheme of each endpoint in its query, like host:port?scheme=https,
+// an endpoint given as scheme://host:port has its scheme moved into the query
+func schemedEndpoints(eps map[string]string) map[string]string {
+	schemed := make(map[string]string, len(eps))
+	for name, ep := range eps {
+		q := endpointQuery(ep)
+		addr := strings.SplitN(ep, "?", 2)[0]
+		if i := strings.Index(addr, protocolSymbol); i != -1 {
+			q.Set(schemeParam, addr[:i])
+		} else if q.Get(schemeParam) == "" {
+			q.Set(schemeParam, protocolScheme(name, ep))
+		}
+		schemed[name] = EndpointAddress(ep) + "?" + q.Encode()
+	}
+	return schemed
+}
+
+// EndpointScheme returns the scheme annotated in endpoint ep, it is empty if ep has none
+func EndpointScheme(ep string) string {
+	return endpointQuery(ep).Get(schemeParam)
+}
@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointScheme(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080", SSLAdvertise: "127.0.0.1:8443"},
+		"grpc":                 {Listen: "127.0.0.1:9090"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:7070"},
+	}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{
+		"rest":     "127.0.0.1:8080?sslEnabled=false",
+		"rest-ssl": "127.0.0.1:8443?sslEnabled=true",
+		"grpc":     "127.0.0.1:9090",
+		"highway":  "127.0.0.1:7070",
+	}, r.instances[0].EndpointsMap, "bare by default")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.EndpointScheme = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{
+		"rest":     "127.0.0.1:8080?scheme=http&sslEnabled=false",
+		"rest-ssl": "127.0.0.1:8443?scheme=https&sslEnabled=true",
+		"grpc":     "127.0.0.1:9090?scheme=grpc",
+		"highway":  "127.0.0.1:7070?scheme=highway",
+	}, r.instances[1].EndpointsMap)
+	assert.Equal(t, "https", EndpointScheme(r.instances[1].EndpointsMap["rest-ssl"]))
+	assert.Equal(t, "", EndpointScheme(r.instances[0].EndpointsMap["rest-ssl"]))
+
+	InstanceEndpoints = map[string]string{"rest": "https://ingress.example.com:443"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{"rest": "ingress.example.com:443?scheme=https"}, r.instances[2].EndpointsMap, "scheme is moved into the query")
+	assert.NoError(t, validEndpoint(r.instances[2].EndpointsMap["rest"]))
+}
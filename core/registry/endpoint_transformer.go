@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// EndpointMapTransformer rewrites the endpoints of self instance before they are registered,
+// like replacing an internal ip with an ingress host name
+type EndpointMapTransformer func(map[string]string) (map[string]string, error)
+
+var endpointMapTransformers []EndpointMapTransformer
+var endpointMapTransformersMu sync.RWMutex
+
+// AddEndpointMapTransformer appends t to the transformers applied to endpoints of self instance,
+// transformers are applied in the order they are added
+func AddEndpointMapTransformer(t EndpointMapTransformer) {
+	endpointMapTransformersMu.Lock()
+	endpointMapTransformers = append(endpointMapTransformers, t)
+	endpointMapTransformersMu.Unlock()
+}
+
+// transformEndpoints applies transformers to eps one by one, it aborts on the first error
+func transformEndpoints(eps map[string]string) (map[string]string, error) {
+	endpointMapTransformersMu.RLock()
+	transformers := make([]EndpointMapTransformer, len(endpointMapTransformers))
+	copy(transformers, endpointMapTransformers)
+	endpointMapTransformersMu.RUnlock()
+	for _, t := range transformers {
+		var err error
+		if eps, err = t(copyMetadata(eps)); err != nil {
+			lager.Logger.Errorf("Transform endpoints failed: %s", err)
+			return nil, err
+		}
+	}
+	return eps, nil
+}
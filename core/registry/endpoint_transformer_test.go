@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointMapTransformer(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer func() { endpointMapTransformers = nil }()
+	AddEndpointMapTransformer(func(eps map[string]string) (map[string]string, error) {
+		for k, v := range eps {
+			eps[k] = strings.Replace(v, "127.0.0.1", "ingress.example.com", 1)
+		}
+		return eps, nil
+	})
+	AddEndpointMapTransformer(func(eps map[string]string) (map[string]string, error) {
+		eps["rest"] += "?sslEnabled=false"
+		return eps, nil
+	})
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{"rest": "ingress.example.com:8080?sslEnabled=false"}, r.instances[0].EndpointsMap)
+
+	called := false
+	AddEndpointMapTransformer(func(eps map[string]string) (map[string]string, error) {
+		return nil, errors.New("no ingress")
+	})
+	AddEndpointMapTransformer(func(eps map[string]string) (map[string]string, error) {
+		called = true
+		return eps, nil
+	})
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.False(t, called)
+	assert.Equal(t, 1, len(r.instances))
+}
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// query parameters of an endpoint
+const (
+	sslEnabledParam = "sslEnabled"
+	weightParam     = "weight"
+	schemeParam     = "scheme"
+)
+
+// DefaultEndpointWeight is the weight of an endpoint which does not declare it
+const DefaultEndpointWeight = 1
+
+// endpointQuery returns the query parameters of an endpoint, like sslEnabled and weight
+func endpointQuery(ep string) url.Values {
+	parts := strings.SplitN(ep, "?", 2)
+	if len(parts) != 2 {
+		return url.Values{}
+	}
+	q, err := url.ParseQuery(parts[1])
+	if err != nil {
+		return url.Values{}
+	}
+	return q
+}
+
+// withEndpointWeight returns ep with weight in its query, other parameters are kept
+func withEndpointWeight(ep string, weight int) string {
+	addr := strings.SplitN(ep, "?", 2)[0]
+	q := endpointQuery(ep)
+	q.Set(weightParam, strconv.Itoa(weight))
+	return addr + "?" + q.Encode()
+}
+
+// EndpointWeight returns the traffic weight encoded in endpoint ep,
+// it is DefaultEndpointWeight if ep declares none, a declared weight must be a positive integer
+func EndpointWeight(ep string) (int, error) {
+	w := endpointQuery(ep).Get(weightParam)
+	if w == "" {
+		return DefaultEndpointWeight, nil
+	}
+	weight, err := strconv.Atoi(w)
+	if err != nil || weight <= 0 {
+		return 0, fmt.Errorf("weight must be a positive integer, got [%s]", w)
+	}
+	return weight, nil
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointWeight(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:            {Listen: "127.0.0.1:8080", SSLAdvertise: "127.0.0.1:8443", Weight: 3},
+		common.ProtocolRest + "-pool2": {Listen: "127.0.0.1:8081", Weight: 1},
+		common.ProtocolHighway:         {Listen: "127.0.0.1:9090"},
+	}
+	eps, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8080?sslEnabled=false&weight=3", eps[common.ProtocolRest])
+	assert.Equal(t, "127.0.0.1:8443?sslEnabled=true&weight=3", eps["rest-ssl"])
+	assert.Equal(t, "127.0.0.1:8081?weight=1", eps["rest-pool2"])
+	assert.Equal(t, "127.0.0.1:9090", eps[common.ProtocolHighway])
+
+	for name, want := range map[string]int{common.ProtocolRest: 3, "rest-ssl": 3, "rest-pool2": 1, common.ProtocolHighway: DefaultEndpointWeight} {
+		w, err := EndpointWeight(eps[name])
+		assert.NoError(t, err)
+		assert.Equal(t, want, w, name)
+	}
+	assert.Equal(t, "true", endpointQuery(eps["rest-ssl"]).Get(sslEnabledParam), "ssl marker is kept")
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, eps, r.instances[0].EndpointsMap)
+	assert.Equal(t, "false", GetSelfMetadata()[MDSecure])
+}
+
+func TestEndpointWeightValidation(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	for _, ep := range []string{"127.0.0.1:8080?weight=0", "127.0.0.1:8080?weight=-1", "127.0.0.1:8080?weight=heavy"} {
+		_, err := EndpointWeight(ep)
+		assert.Error(t, err, ep)
+		assert.Error(t, validEndpoint(ep), ep)
+	}
+
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1:8080", Weight: -2},
+	}
+	err := ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cse.protocols.rest.weight")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Empty(t, r.instances)
+}
@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/go-chassis/go-chassis/core/config/model"
+)
+
+// healthPathKey returns the metadata key of the health check path of protocol name, like health.rest
+func healthPathKey(name string) string {
+	return MDHealthPath + "." + name
+}
+
+// validHealthPath checks the health path begins with slash and has no white space
+func validHealthPath(p string) error {
+	if !strings.HasPrefix(p, "/") {
+		return fmt.Errorf("health path [%s] must begin with /", p)
+	}
+	if strings.IndexFunc(p, unicode.IsSpace) != -1 {
+		return fmt.Errorf("health path [%s] must not contain white space", p)
+	}
+	return nil
+}
+
+// healthPaths returns the health check path to advertise of each protocol declaring it
+func healthPaths(protocols map[string]model.Protocol) (map[string]string, error) {
+	paths := make(map[string]string)
+	for name, p := range protocols {
+		if p.HealthPath == "" {
+			continue
+		}
+		if err := validHealthPath(p.HealthPath); err != nil {
+			return nil, err
+		}
+		paths[name] = p.HealthPath
+	}
+	return paths, nil
+}
@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthPathMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080", HealthPath: "/healthz"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090", HealthPath: "/health"},
+		"grpc":                 {Listen: "127.0.0.1:7070"},
+	}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md := r.instances[0].Metadata
+	assert.Equal(t, "/healthz", md["health.rest"])
+	assert.Equal(t, "/health", md["health.highway"])
+	assert.NotContains(t, md, "health.grpc")
+}
+
+func TestHealthPathValidation(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080", HealthPath: "healthz"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090", HealthPath: "/health check"},
+	}
+	err := ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cse.protocols.rest.healthPath")
+	assert.Contains(t, err.Error(), "cse.protocols.highway.healthPath")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Empty(t, r.instances)
+}
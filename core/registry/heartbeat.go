@@ -5,7 +5,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-chassis/go-chassis/core/config"
 	"github.com/go-chassis/go-chassis/core/lager"
 
 	"github.com/go-chassis/go-chassis/core/common"
@@ -52,7 +51,7 @@ func (s *HeartbeatService) AddTask(microServiceID, microServiceInstanceID string
 		s.instances[key] = &HeartbeatTask{
 			ServiceID:  microServiceID,
 			InstanceID: microServiceInstanceID,
-			Time:       time.Now(),
+			Time:       nowFunc(),
 		}
 	}
 	s.mux.Unlock()
@@ -66,12 +65,23 @@ func (s *HeartbeatService) RemoveTask(microServiceID, microServiceInstanceID str
 	s.mux.Unlock()
 }
 
+// removeServiceTasks removes all instances of micro-service from the heartbeat system
+func (s *HeartbeatService) removeServiceTasks(microServiceID string) {
+	s.mux.Lock()
+	for key, task := range s.instances {
+		if task.ServiceID == microServiceID {
+			delete(s.instances, key)
+		}
+	}
+	s.mux.Unlock()
+}
+
 // RefreshTask refresh heartbeat for micro-service instance
 func (s *HeartbeatService) RefreshTask(microServiceID, microServiceInstanceID string) {
 	key := fmt.Sprintf("%s/%s", microServiceID, microServiceInstanceID)
 	s.mux.Lock()
 	if _, ok := s.instances[key]; ok {
-		s.instances[key].Time = time.Now()
+		s.instances[key].Time = nowFunc()
 	}
 	s.mux.Unlock()
 }
@@ -103,7 +113,7 @@ func (s *HeartbeatService) DoHeartBeat(microServiceID, microServiceInstanceID st
 func (s *HeartbeatService) run() {
 	for !s.shutdown {
 		s.mux.Lock()
-		endTime := time.Now()
+		endTime := nowFunc()
 		for _, v := range s.instances {
 			if v.Running {
 				continue
@@ -117,12 +127,13 @@ func (s *HeartbeatService) run() {
 	}
 }
 
-// RetryRegister retrying to register micro-service, and instance
+// RetryRegister retrying to register micro-service, and instance,
+// it stops once the retry classifier tells the error is not retryable
 func (s *HeartbeatService) RetryRegister(sid, iid string) error {
 	for {
 		time.Sleep(DefaultRetryTime)
 		lager.Logger.Infof("Try to re-register self")
-		_, err := DefaultServiceDiscoveryService.GetAllMicroServices()
+		err := registryReachable()
 		if err != nil {
 			lager.Logger.Errorf("DefaultRegistrator is not healthy %s", err)
 			continue
@@ -135,11 +146,24 @@ func (s *HeartbeatService) RetryRegister(sid, iid string) error {
 		if err == nil {
 			break
 		}
+		if !retryable(err) {
+			lager.Logger.Errorf("Re-register self failed with non-retryable error, give up: %s", err)
+			return err
+		}
 	}
 	lager.Logger.Warn("Re-register self success")
 	return nil
 }
 
+// registryReachable checks registry with Ping if registrator supports it, otherwise by listing micro-services
+func registryReachable() error {
+	if p, ok := asPinger(DefaultRegistrator); ok {
+		return p.Ping()
+	}
+	_, err := DefaultServiceDiscoveryService.GetAllMicroServices()
+	return err
+}
+
 // ReRegisterSelfMSandMSI 重新注册微服务和实例
 func (s *HeartbeatService) ReRegisterSelfMSandMSI() error {
 	err := RegisterMicroservice()
@@ -157,44 +181,39 @@ func (s *HeartbeatService) ReRegisterSelfMSandMSI() error {
 }
 
 // reRegisterSelfMSI 只重新注册实例
+// it is assembled, signed and encoded as at registration, keeping its id, status and the metadata updated at runtime
 func reRegisterSelfMSI(sid, iid string) error {
-	eps, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	microServiceInstance, instanceProperties, err := assembleInstance()
 	if err != nil {
 		return err
 	}
-	if InstanceEndpoints != nil {
-		eps = InstanceEndpoints
+	microServiceInstance.InstanceID = iid
+	if runtime.InstanceStatus != "" {
+		microServiceInstance.Status = runtime.InstanceStatus
 	}
-	microServiceInstance := &MicroServiceInstance{
-		InstanceID:   iid,
-		EndpointsMap: eps,
-		HostName:     runtime.HostName,
-		Status:       common.DefaultStatus,
+	for k, v := range runtimeMetadata() {
+		microServiceInstance.Metadata[k] = v
 	}
-	instanceID, err := DefaultRegistrator.RegisterServiceInstance(sid, microServiceInstance)
+	selfMD, err := sealInstance(microServiceInstance)
 	if err != nil {
-		lager.Logger.Errorf("RegisterInstance failed: %s", err)
 		return err
 	}
-
-	value, ok := SelfInstancesCache.Get(microServiceInstance.ServiceID)
-	if !ok {
-		lager.Logger.Warnf("RegisterMicroServiceInstance get SelfInstancesCache failed, microServiceID/instanceID: %s/%s", sid, instanceID)
-	}
-	instanceIDs, ok := value.([]string)
-	if !ok {
-		lager.Logger.Warnf("RegisterMicroServiceInstance type asserts failed, microServiceID/instanceID: %s/%s", sid, instanceID)
-	}
-	var isRepeat bool
-	for _, va := range instanceIDs {
-		if va == instanceID {
-			isRepeat = true
-		}
+	key := idempotencyKey(PhaseInstance)
+	instanceID, err := defaultRunner().registerInstance(key, sid, microServiceInstance)
+	if err != nil {
+		lager.Logger.Errorf("RegisterInstance failed: %s", err)
+		return err
 	}
-	if !isRepeat {
-		instanceIDs = append(instanceIDs, instanceID)
+	if instanceID == "" {
+		lager.Logger.Errorf("RegisterInstance failed: %s", ErrEmptyInstanceID)
+		return ErrEmptyInstanceID
 	}
-	SelfInstancesCache.Set(microServiceInstance.ServiceID, instanceIDs, 0)
+	finishIdempotencyKey(PhaseInstance)
+	setSelfMetadata(selfMD, instanceProperties)
+	setSelfEndpoints(instanceID, microServiceInstance.EndpointsMap)
+
+	addSelfInstanceID(sid, instanceID)
+	reconcileLateInstances(DefaultRegistrator, instanceID)
 	lager.Logger.Warnf("RegisterMicroServiceInstance success, microServiceID/instanceID: %s/%s.", sid, instanceID)
 
 	return nil
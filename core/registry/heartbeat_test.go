@@ -22,6 +22,8 @@ func TestServicecenter_Heartbeat(t *testing.T) {
 	runtime.Init()
 	t.Log(os.Getenv("CHASSIS_HOME"))
 	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	// bound registration retries in case service center is unreachable
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "1s"
 	registry.Enable()
 	registry.DoRegister()
 
@@ -59,6 +61,8 @@ func TestServicecenter_HeartbeatUpdatProperties(t *testing.T) {
 	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = ins
 	t.Log(os.Getenv("CHASSIS_HOME"))
 	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	// bound registration retries in case service center is unreachable
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "1s"
 	registry.Enable()
 	registry.DoRegister()
 
@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// IdempotentRegistrator is implemented by registrators which dedupe registration by idempotency key,
+// retries of one registration carry the same key
+type IdempotentRegistrator interface {
+	RegisterServiceWithKey(key string, microService *MicroService) (string, error)
+	RegisterServiceInstanceWithKey(key, microServiceID string, instance *MicroServiceInstance) (string, error)
+}
+
+// idempotencyKeys holds the key of the ongoing registration of each phase
+var idempotencyKeys = make(map[string]string)
+var idempotencyMu sync.Mutex
+
+// newIdempotencyKey generates a random key
+var newIdempotencyKey = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// idempotencyKey returns the key of the ongoing registration of phase,
+// the key is kept across retries until the registration succeeds
+func idempotencyKey(phase string) string {
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	key, ok := idempotencyKeys[phase]
+	if !ok {
+		key = newIdempotencyKey()
+		idempotencyKeys[phase] = key
+	}
+	return key
+}
+
+// finishIdempotencyKey drops the key of phase after registration succeeds,
+// so that next registration uses a new key
+func finishIdempotencyKey(phase string) {
+	idempotencyMu.Lock()
+	delete(idempotencyKeys, phase)
+	idempotencyMu.Unlock()
+}
+
+// registerService registers micro-service under the timeout of OpRegisterService, with key if registrator supports it
+func (r *RegistrationRunner) registerService(key string, ms *MicroService) (string, error) {
+	if cr, ok := asContextRegistrator(r.Registrator); ok {
+		return callContextForID(OpRegisterService, key, func(ctx context.Context) (string, error) {
+			return cr.RegisterServiceWithContext(ctx, ms)
+		})
+	}
+	return callForID(OpRegisterService, func() (string, error) {
+		if ir, ok := asIdempotentRegistrator(r.Registrator); ok {
+			return ir.RegisterServiceWithKey(key, ms)
+		}
+		return r.Registrator.RegisterService(ms)
+	})
+}
+
+// registerInstance registers micro-service instance under the timeout of OpRegisterInstance, with key if registrator supports it
+func (r *RegistrationRunner) registerInstance(key, sid string, ins *MicroServiceInstance) (string, error) {
+	if cr, ok := asContextRegistrator(r.Registrator); ok {
+		return callContextForID(OpRegisterInstance, key, func(ctx context.Context) (string, error) {
+			return cr.RegisterServiceInstanceWithContext(ctx, sid, ins)
+		})
+	}
+	_, iid, err := callForIDs(OpRegisterInstance, func() (string, string, error) {
+		iid, err := r.registerInstanceWithKey(key, sid, ins)
+		return sid, iid, err
+	})
+	return iid, err
+}
+
+func (r *RegistrationRunner) registerInstanceWithKey(key, sid string, ins *MicroServiceInstance) (string, error) {
+	if ir, ok := asIdempotentRegistrator(r.Registrator); ok {
+		return ir.RegisterServiceInstanceWithKey(key, sid, ins)
+	}
+	return r.Registrator.RegisterServiceInstance(sid, ins)
+}
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIdempotentRegistrator records idempotency keys of registration calls
+type fakeIdempotentRegistrator struct {
+	*fakeRegistrator
+	serviceKeys  []string
+	instanceKeys []string
+}
+
+func (f *fakeIdempotentRegistrator) RegisterServiceWithKey(key string, ms *MicroService) (string, error) {
+	f.serviceKeys = append(f.serviceKeys, key)
+	return f.RegisterService(ms)
+}
+
+func (f *fakeIdempotentRegistrator) RegisterServiceInstanceWithKey(key, sid string, ins *MicroServiceInstance) (string, error) {
+	f.instanceKeys = append(f.instanceKeys, key)
+	return f.RegisterServiceInstance(sid, ins)
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	r, d := initBootstrapEnv()
+	idempotencyKeys = make(map[string]string)
+	ir := &fakeIdempotentRegistrator{fakeRegistrator: r}
+	runner := NewRegistrationRunner(ir, d)
+
+	r.err = errors.New("response lost")
+	assert.Error(t, runner.RegisterMicroservice())
+	assert.Error(t, runner.RegisterMicroservice())
+	r.err = nil
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.Equal(t, 4, len(ir.serviceKeys))
+	assert.NotEmpty(t, ir.serviceKeys[0])
+	assert.Equal(t, ir.serviceKeys[0], ir.serviceKeys[1], "stable across retries")
+	assert.Equal(t, ir.serviceKeys[0], ir.serviceKeys[2], "stable across retries")
+	assert.NotEqual(t, ir.serviceKeys[2], ir.serviceKeys[3], "changes across registrations")
+
+	r.err = errors.New("response lost")
+	assert.Error(t, runner.RegisterMicroserviceInstances())
+	r.err = nil
+	assert.NoError(t, runner.RegisterMicroserviceInstances())
+	assert.NoError(t, runner.RegisterMicroserviceInstances())
+	assert.Equal(t, ir.instanceKeys[0], ir.instanceKeys[1])
+	assert.NotEqual(t, ir.instanceKeys[1], ir.instanceKeys[2])
+	assert.NotEqual(t, ir.serviceKeys[3], ir.instanceKeys[2])
+}
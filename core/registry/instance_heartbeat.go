@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// states of instance heartbeat
+const (
+	HeartbeatStopped = "stopped"
+	HeartbeatPending = "pending"
+	HeartbeatAlive   = "alive"
+	HeartbeatFailed  = "failed"
+)
+
+// ErrHeartbeatRunning means instance heartbeat is already started
+var ErrHeartbeatRunning = errors.New("instance heartbeat is already running")
+
+// DefaultInstanceHeartbeat refreshes the ttl of self instance, it is started by DoRegister if heartbeatInterval is set
+var DefaultInstanceHeartbeat = &InstanceHeartbeat{}
+
+// InstanceHeartbeat sends heartbeat of self instance at the interval of heartbeatInterval,
+// so that registry never expires a healthy instance
+type InstanceHeartbeat struct {
+	mu    sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+	state string
+	err   error
+}
+
+// heartbeatInterval returns the configured interval, 30s by default
+func heartbeatInterval() (time.Duration, error) {
+	s := config.GetRegistratorHeartbeatInterval()
+	if s == "" {
+		return common.DefaultHBInterval * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("heartbeat interval is invalid [%s]: %s", s, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("heartbeat interval must be positive, got [%s]", s)
+	}
+	return d, nil
+}
+
+// heartbeatInitialDelay returns the configured delay of the first heartbeat, 0 by default
+func heartbeatInitialDelay() (time.Duration, error) {
+	s := config.GetRegistratorHeartbeatInitialDelay()
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("heartbeat initial delay is invalid [%s]: %s", s, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("heartbeat initial delay must not be negative, got [%s]", s)
+	}
+	return d, nil
+}
+
+// Start starts sending heartbeat in background,
+// if heartbeatInitialDelay is set the first heartbeat is sent after it, otherwise after one interval
+func (h *InstanceHeartbeat) Start() error {
+	if registrationSkipped() {
+		lager.Logger.Info("Registration is disabled, instance heartbeat is not started")
+		return nil
+	}
+	interval, err := heartbeatInterval()
+	if err != nil {
+		return err
+	}
+	delay, err := heartbeatInitialDelay()
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stop != nil {
+		return ErrHeartbeatRunning
+	}
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+	h.state, h.err = HeartbeatPending, nil
+	go h.run(delay, interval, h.stop, h.done)
+	lager.Logger.Infof("Start instance heartbeat every %s, initial delay %s", interval, delay)
+	return nil
+}
+
+// Stop stops sending heartbeat and waits for the running one to finish
+func (h *InstanceHeartbeat) Stop() {
+	h.mu.Lock()
+	stop, done := h.stop, h.done
+	h.stop, h.done = nil, nil
+	h.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	h.setStatus(HeartbeatStopped, nil)
+	lager.Logger.Info("Instance heartbeat stopped")
+}
+
+// Status returns the state of heartbeat, and the error of last heartbeat if it failed
+func (h *InstanceHeartbeat) Status() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state == "" {
+		return HeartbeatStopped, nil
+	}
+	return h.state, h.err
+}
+
+func (h *InstanceHeartbeat) setStatus(state string, err error) {
+	h.mu.Lock()
+	h.state, h.err = state, err
+	h.mu.Unlock()
+}
+
+func (h *InstanceHeartbeat) run(delay, interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	if delay > 0 {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+			h.beat()
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+// beat sends one heartbeat of self instance, it is skipped before self instance is registered or if registration is disabled
+func (h *InstanceHeartbeat) beat() {
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" || registrationSkipped() {
+		return
+	}
+	err := registrationBreaker.call(func() error {
+		ok, err := DefaultRegistrator.Heartbeat(sid, iid)
+		if err == nil && !ok {
+			err = fmt.Errorf("heartbeat of %s/%s is not accepted", sid, iid)
+		}
+		return err
+	})
+	if err != nil {
+		lager.Logger.Warnf("Instance heartbeat failed: %s", err)
+		h.setStatus(HeartbeatFailed, err)
+		return
+	}
+	h.setStatus(HeartbeatAlive, nil)
+}
@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func heartbeatCount(r *fakeRegistrator) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.heartbeats)
+}
+
+func TestInstanceHeartbeat(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval = "20ms"
+	h := &InstanceHeartbeat{}
+	state, _ := h.Status()
+	assert.Equal(t, HeartbeatStopped, state)
+
+	assert.NoError(t, h.Start())
+	defer h.Stop()
+	assert.Equal(t, ErrHeartbeatRunning, h.Start())
+	// skipped before self instance is registered
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, heartbeatCount(r))
+	state, _ = h.Status()
+	assert.Equal(t, HeartbeatPending, state)
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	time.Sleep(110 * time.Millisecond)
+	n := heartbeatCount(r)
+	assert.True(t, n >= 3 && n <= 6, "heartbeat every 20ms, got %d", n)
+	r.mu.Lock()
+	for i := 1; i < len(r.heartbeats); i++ {
+		assert.True(t, r.heartbeats[i].Sub(r.heartbeats[i-1]) >= 10*time.Millisecond)
+	}
+	r.mu.Unlock()
+	state, err := h.Status()
+	assert.Equal(t, HeartbeatAlive, state)
+	assert.NoError(t, err)
+
+	r.mu.Lock()
+	r.hbErr = errors.New("expired")
+	r.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	state, err = h.Status()
+	assert.Equal(t, HeartbeatFailed, state)
+	assert.Error(t, err)
+
+	h.Stop()
+	n = heartbeatCount(r)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, n, heartbeatCount(r), "no heartbeat after stop")
+	state, _ = h.Status()
+	assert.Equal(t, HeartbeatStopped, state)
+	// restartable
+	assert.NoError(t, h.Start())
+}
+
+func TestInstanceHeartbeatInterval(t *testing.T) {
+	initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval = "soon"
+	assert.Error(t, (&InstanceHeartbeat{}).Start())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval = "-1s"
+	assert.Error(t, (&InstanceHeartbeat{}).Start())
+}
+
+func TestInstanceHeartbeatInitialDelay(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval = "20ms"
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInitialDelay = "100ms"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	h := &InstanceHeartbeat{}
+	start := time.Now()
+	assert.NoError(t, h.Start())
+	defer h.Stop()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 0, heartbeatCount(r), "no heartbeat within initial delay")
+	time.Sleep(80 * time.Millisecond)
+	assert.True(t, heartbeatCount(r) >= 1)
+	r.mu.Lock()
+	assert.True(t, r.heartbeats[0].Sub(start) >= 100*time.Millisecond)
+	r.mu.Unlock()
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInitialDelay = "later"
+	assert.Error(t, (&InstanceHeartbeat{}).Start())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInitialDelay = "-1s"
+	assert.Error(t, (&InstanceHeartbeat{}).Start())
+}
+
+func TestDoRegisterStartsHeartbeat(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	resetCompletion()
+	defer resetCompletion()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.HeartbeatInterval = "1h"
+	defer DefaultInstanceHeartbeat.Stop()
+	assert.NoError(t, RegisterMicroservice())
+
+	done := make(chan error, 1)
+	OnRegistrationComplete(func(err error) { done <- err })
+	r.err = ErrCrossAppNotAccepted
+	assert.NoError(t, DoRegister())
+	assert.Equal(t, ErrCrossAppNotAccepted, <-done)
+	state, _ := DefaultInstanceHeartbeat.Status()
+	assert.Equal(t, HeartbeatStopped, state, "not started before self instance is registered")
+
+	resetCompletion()
+	r.err = nil
+	assert.NoError(t, DoRegister())
+	state, _ = DefaultInstanceHeartbeat.Status()
+	assert.Equal(t, HeartbeatPending, state)
+}
@@ -0,0 +1,568 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+	chassisTLS "github.com/go-chassis/go-chassis/core/tls"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// ErrInstanceNotRegistered means self instance has not been registered yet
+var ErrInstanceNotRegistered = errors.New("self instance is not registered")
+
+// selfMetadata is the effective metadata of self instance in registry,
+// selfProperties are the keys of it coming from instance_properties,
+// selfMetadataVersion is increased on every change of them
+var selfMetadata = make(map[string]string)
+var selfProperties = make(map[string]string)
+var selfMetadataVersion uint64
+var selfMetadataMu sync.RWMutex
+
+// maxMetadataUpdateAttempts bounds how many times an update starts over when self metadata changes during it
+const maxMetadataUpdateAttempts = 3
+
+// updateSelfMetadata derives metadata and properties of self instance from a snapshot with change,
+// pushes the metadata with push and records both, the lock is not held while pushing,
+// the update starts over from the new metadata if it is changed meanwhile
+func updateSelfMetadata(change func(md, properties map[string]string) (map[string]string, map[string]string),
+	push func(md map[string]string) error) error {
+	for i := 0; i < maxMetadataUpdateAttempts; i++ {
+		selfMetadataMu.RLock()
+		md, properties := change(copyMetadata(selfMetadata), copyMetadata(selfProperties))
+		version := selfMetadataVersion
+		selfMetadataMu.RUnlock()
+		if err := push(md); err != nil {
+			return err
+		}
+		selfMetadataMu.Lock()
+		if version == selfMetadataVersion {
+			selfMetadata, selfProperties = md, properties
+			selfMetadataVersion++
+			selfMetadataMu.Unlock()
+			return nil
+		}
+		selfMetadataMu.Unlock()
+		lager.Logger.Debugf("Self metadata changed during update, update again")
+	}
+	return errors.New("self metadata kept changing during update")
+}
+
+// buildInstanceMetadata assembles the chassis managed metadata of self instance
+func buildInstanceMetadata() (map[string]string, error) {
+	md := map[string]string{
+		chassisKey(MDNodeIP): nodeIP(),
+	}
+	// start time differs on each restart, it is opt-in so that re-registration stays idempotent
+	if config.GetRegistratorStartTime() {
+		md[chassisKey(MDStartTime)] = nowFunc().UTC().Format(time.RFC3339)
+	}
+	ins := config.MicroserviceDefinition.ServiceDescription.Instance
+	if ins.Capacity != 0 {
+		if ins.Capacity < 0 {
+			return nil, fmt.Errorf("capacity must be a positive integer, got %d", ins.Capacity)
+		}
+		md[chassisKey(MDCapacity)] = strconv.Itoa(ins.Capacity)
+	}
+	// maxConcurrency is validated along with the registration config
+	if n := config.MicroserviceDefinition.ServiceDescription.MaxConcurrency; n > 0 {
+		md[chassisKey(MDMaxConcurrency)] = strconv.Itoa(n)
+	}
+	if len(ins.Tags) != 0 {
+		tags, err := normalizeTags(ins.Tags)
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDTags)] = tags
+	}
+	if len(ins.Encodings) != 0 {
+		encodings, err := normalizeEncodings(ins.Encodings)
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDEncodings)] = encodings
+	}
+	if ins.TrafficPercent != nil {
+		percent, err := validTrafficPercent(strconv.Itoa(*ins.TrafficPercent))
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDTrafficPercent)] = percent
+	}
+	if ins.Priority != nil {
+		priority, err := validPriority(strconv.Itoa(*ins.Priority))
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDPriority)] = priority
+	}
+	if len(ins.Flags) != 0 {
+		flags, err := validFlags(strings.Join(ins.Flags, ","))
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDFlags)] = flags
+	}
+	if ins.Cohort != "" {
+		cohort := strings.TrimSpace(ins.Cohort)
+		if cohort == "" {
+			return nil, errors.New("cohort must not be blank")
+		}
+		// progressive delivery controllers target the instances of a cohort for canary analysis
+		md[chassisKey(MDCohort)] = cohort
+	}
+	if ins.Locale != "" {
+		locale, err := validLocale(ins.Locale)
+		if err != nil {
+			return nil, err
+		}
+		// geo routers prefer instances of the locale of a request
+		md[chassisKey(MDLocale)] = locale
+	}
+	if ins.Timezone != "" {
+		tz, err := validTimezone(ins.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDTimezone)] = tz
+	}
+	if len(ins.RegionPreference) != 0 {
+		regions, err := normalizeRegionPreference(ins.RegionPreference)
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDRegionPreference)] = regions
+	}
+	if ins.NodeID.Enabled {
+		id, err := nodeID(ins.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		md[chassisKey(MDNodeID)] = id
+	}
+	grace, err := ShutdownGrace()
+	if err != nil {
+		return nil, err
+	}
+	if grace != 0 {
+		md[chassisKey(MDShutdownGrace)] = grace.String()
+	}
+	warmup, err := warmupDuration()
+	if err != nil {
+		return nil, err
+	}
+	if warmup != 0 {
+		// routers ramp traffic to a new instance up over the warm-up duration
+		md[chassisKey(MDWarmup)] = warmup.String()
+	}
+	paths, err := basePaths(config.GlobalDefinition.Cse.Protocols)
+	if err != nil {
+		return nil, err
+	}
+	for name, p := range paths {
+		md[chassisKey(basePathKey(name))] = p
+	}
+	health, err := healthPaths(config.GlobalDefinition.Cse.Protocols)
+	if err != nil {
+		return nil, err
+	}
+	for name, p := range health {
+		md[chassisKey(healthPathKey(name))] = p
+	}
+	affinity, err := affinityMetadata(ins.Affinity)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range affinity {
+		md[chassisKey(k)] = v
+	}
+	for _, provide := range metadataProviders {
+		for k, v := range provide() {
+			md[chassisKey(k)] = v
+		}
+	}
+	facts, err := fileMetadata()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range facts {
+		if _, ok := md[k]; !ok {
+			md[k] = v
+		}
+	}
+	return md, nil
+}
+
+// secureEndpoints tells whether all advertised endpoints are TLS, mixed endpoints are not secure
+func secureEndpoints(eps map[string]string) bool {
+	var tlsEndpoints []string
+	for name, ep := range eps {
+		if endpointTLS(name, ep) {
+			tlsEndpoints = append(tlsEndpoints, name)
+		}
+	}
+	if len(tlsEndpoints) == 0 {
+		return false
+	}
+	if len(tlsEndpoints) != len(eps) {
+		sort.Strings(tlsEndpoints)
+		lager.Logger.Warnf("only endpoints %v are TLS, instance is not secure", tlsEndpoints)
+		return false
+	}
+	return true
+}
+
+// endpointTLS tells whether endpoint ep of protocol name is TLS,
+// it is if ep is marked with sslEnabled=true, or it is not marked and its protocol server has ssl config
+func endpointTLS(name, ep string) bool {
+	switch endpointQuery(ep).Get(sslEnabledParam) {
+	case "true":
+		return true
+	case "false":
+		return false
+	default:
+		_, err := chassisTLS.GetSSLConfigByService("", name, common.Provider)
+		return err == nil
+	}
+}
+
+// defaultNodeIDEnv is the env var node id is read from if no env is configured
+const defaultNodeIDEnv = "NODE_ID"
+
+// nodeID returns the configured node id, or the one read from env,
+// it must not be empty
+func nodeID(n model.NodeIDStruct) (string, error) {
+	id := strings.TrimSpace(n.Value)
+	if id == "" {
+		env := envName(n.Env, defaultNodeIDEnv)
+		id = strings.TrimSpace(os.Getenv(env))
+		if id == "" {
+			return "", fmt.Errorf("node id is enabled, but neither value nor env [%s] is set", env)
+		}
+	}
+	return id, nil
+}
+
+// knownEncodings are the payload encodings an instance is allowed to advertise
+var knownEncodings = map[string]bool{
+	"gzip":    true,
+	"deflate": true,
+	"snappy":  true,
+	"br":      true,
+	"zstd":    true,
+}
+
+// normalizeEncodings lower cases supported encodings and joins them with comma,
+// encodings must be known and unique
+func normalizeEncodings(encodings []string) (string, error) {
+	seen := make(map[string]bool, len(encodings))
+	normalized := make([]string, 0, len(encodings))
+	for _, e := range encodings {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if !knownEncodings[e] {
+			return "", fmt.Errorf("unknown encoding [%s]", e)
+		}
+		if seen[e] {
+			return "", fmt.Errorf("duplicated encoding [%s]", e)
+		}
+		seen[e] = true
+		normalized = append(normalized, e)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// normalizeTags trims instance tags and joins them with comma,
+// tags must be non-empty, unique and must not contain comma
+func normalizeTags(tags []string) (string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return "", errors.New("instance tag must not be empty")
+		}
+		if strings.Contains(t, ",") {
+			return "", fmt.Errorf("instance tag [%s] must not contain comma", t)
+		}
+		if seen[t] {
+			return "", fmt.Errorf("duplicated instance tag [%s]", t)
+		}
+		seen[t] = true
+		normalized = append(normalized, t)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// normalizeRegionPreference trims the region failover preference and joins it with comma keeping the order,
+// regions must be non-empty, unique and must not contain comma
+func normalizeRegionPreference(regions []string) (string, error) {
+	seen := make(map[string]bool, len(regions))
+	normalized := make([]string, 0, len(regions))
+	for _, r := range regions {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			return "", errors.New("preferred region must not be empty")
+		}
+		if strings.Contains(r, ",") {
+			return "", fmt.Errorf("preferred region [%s] must not contain comma", r)
+		}
+		if seen[r] {
+			return "", fmt.Errorf("duplicated preferred region [%s]", r)
+		}
+		seen[r] = true
+		normalized = append(normalized, r)
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// warmupDuration returns the declared warm-up duration of self instance, 0 if it is not declared
+func warmupDuration() (time.Duration, error) {
+	s := config.MicroserviceDefinition.ServiceDescription.Instance.Warmup
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid warmup [%s]: %s", s, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("warmup must not be negative, got %s", s)
+	}
+	return d, nil
+}
+
+// setSelfMetadata records the metadata registered for self instance, and the instance properties in it
+func setSelfMetadata(md, properties map[string]string) {
+	selfMetadataMu.Lock()
+	selfMetadata = copyMetadata(md)
+	selfProperties = copyMetadata(properties)
+	selfMetadataVersion++
+	selfMetadataMu.Unlock()
+}
+
+// runtimeMetadata returns a copy of self metadata without the keys of instance properties,
+// they are the chassis managed and runtime updated keys kept when self instance is registered again
+func runtimeMetadata() map[string]string {
+	selfMetadataMu.RLock()
+	defer selfMetadataMu.RUnlock()
+	md := copyMetadata(selfMetadata)
+	for k := range selfProperties {
+		delete(md, k)
+	}
+	return md
+}
+
+// GetSelfMetadata returns a copy of the effective metadata of self instance
+func GetSelfMetadata() map[string]string {
+	selfMetadataMu.RLock()
+	defer selfMetadataMu.RUnlock()
+	return copyMetadata(selfMetadata)
+}
+
+// selfInstance returns self instance iid with endpoints eps, the current status and a copy of plain metadata md,
+// as it is sent to registry again after registration
+func selfInstance(iid string, eps, md map[string]string) *MicroServiceInstance {
+	status := runtime.InstanceStatus
+	if status == "" {
+		status = common.DefaultStatus
+	}
+	return &MicroServiceInstance{
+		InstanceID:   iid,
+		EndpointsMap: eps,
+		HostName:     runtime.HostName,
+		Status:       status,
+		Metadata:     copyMetadata(md),
+	}
+}
+
+// registeredSelfMetadata returns plain metadata md of self instance in status as it is pushed to registry,
+// registry replaces the whole metadata with it, so it is signed again along with self instance and encoded,
+// empty status means the current status
+func registeredSelfMetadata(status string, md map[string]string) (map[string]string, error) {
+	return registeredInstanceMetadata(status, GetAdvertisedEndpoints(), md)
+}
+
+// registeredInstanceMetadata is registeredSelfMetadata of self instance advertising eps
+func registeredInstanceMetadata(status string, eps, md map[string]string) (map[string]string, error) {
+	_, iid := selfIDs()
+	ins := selfInstance(iid, eps, md)
+	if status != "" {
+		ins.Status = status
+	}
+	if err := signInstance(ins); err != nil {
+		return nil, err
+	}
+	return encodeMetadata(ins.Metadata)
+}
+
+// policies of instance properties when registrator does not implement InstancePropertiesUpdater
+const (
+	// UnsupportedPropertiesSkip logs a warning and keeps instance properties local, it is the default policy
+	UnsupportedPropertiesSkip = "skip"
+	// UnsupportedPropertiesFail fails the update with ErrPropertiesUnsupported
+	UnsupportedPropertiesFail = "fail"
+)
+
+// ErrPropertiesUnsupported means registrator is not able to update instance properties
+var ErrPropertiesUnsupported = errors.New("registrator does not support updating instance properties")
+
+// updateInstanceProperties pushes md as the properties of instance sid/iid with reg,
+// if reg does not implement InstancePropertiesUpdater or returns ErrPropertiesUnsupported,
+// it is handled according to unsupportedProperties policy,
+// nothing is pushed if registration is disabled
+func updateInstanceProperties(reg Registrator, sid, iid string, md map[string]string) error {
+	if registrationSkipped() {
+		lager.Logger.Debugf("Registration is disabled, properties of %s/%s are not pushed", sid, iid)
+		return nil
+	}
+	err := ErrPropertiesUnsupported
+	if updater, ok := asInstancePropertiesUpdater(reg); ok {
+		err = updater.UpdateMicroServiceInstanceProperties(sid, iid, md)
+	}
+	if err != ErrPropertiesUnsupported {
+		return err
+	}
+	switch policy := config.GetRegistratorUnsupportedProperties(); policy {
+	case "", UnsupportedPropertiesSkip:
+		lager.Logger.Warnf("Registrator does not support updating instance properties, properties of %s/%s are not pushed", sid, iid)
+		return nil
+	case UnsupportedPropertiesFail:
+		return ErrPropertiesUnsupported
+	default:
+		return fmt.Errorf("unknown unsupported properties policy [%s]", policy)
+	}
+}
+
+// updatableKeys are chassis managed keys which can be updated at runtime, with their validators
+var updatableKeys = map[string]func(string) (string, error){
+	MDTrafficPercent: validTrafficPercent,
+	MDPriority:       validPriority,
+	MDFlags:          validFlags,
+}
+
+// UpdateInstanceMetadata merges delta into the metadata of self instance and pushes it to registry,
+// keys not in delta are kept as they are, self instance is not re-registered,
+// trafficPercent, priority and flags are validated and written as chassis managed keys
+func UpdateInstanceMetadata(delta map[string]string) error {
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	delta, err := registeredDelta(delta)
+	if err != nil {
+		return err
+	}
+	err = updateSelfMetadata(func(md, properties map[string]string) (map[string]string, map[string]string) {
+		return mergeMetadata(md, delta), properties
+	}, func(md map[string]string) error {
+		encoded, err := registeredSelfMetadata("", md)
+		if err != nil {
+			return err
+		}
+		return updateInstanceProperties(DefaultRegistrator, sid, iid, encoded)
+	})
+	if err != nil {
+		lager.Logger.Errorf("Update instance metadata failed, microServiceID/instanceID = %s/%s: %s", sid, iid, err)
+		return err
+	}
+	lager.Logger.Debugf("Update instance metadata success, delta %v", delta)
+	return nil
+}
+
+// registeredDelta validates a metadata delta and returns it with registered keys,
+// updatable chassis managed keys are prefixed, the others are checked against reserved keys
+func registeredDelta(delta map[string]string) (map[string]string, error) {
+	managed := make(map[string]string)
+	user := make(map[string]string, len(delta))
+	for k, v := range delta {
+		validate, ok := updatableKeys[k]
+		if !ok {
+			user[k] = v
+			continue
+		}
+		v, err := validate(v)
+		if err != nil {
+			return nil, err
+		}
+		managed[chassisKey(k)] = v
+	}
+	registered, err := checkReservedKeys(userMetadata(user))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range managed {
+		registered[k] = v
+	}
+	return registered, nil
+}
+
+// mergeMetadata returns a copy of md with delta applied
+func mergeMetadata(md, delta map[string]string) map[string]string {
+	merged := copyMetadata(md)
+	for k, v := range delta {
+		merged[k] = v
+	}
+	return merged
+}
+
+func copyMetadata(md map[string]string) map[string]string {
+	c := make(map[string]string, len(md))
+	for k, v := range md {
+		c[k] = v
+	}
+	return c
+}
+
+// validTrafficPercent checks the traffic percentage is an integer between 0 and 100
+func validTrafficPercent(s string) (string, error) {
+	percent, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || percent < 0 || percent > 100 {
+		return "", fmt.Errorf("traffic percent must be an integer between 0 and 100, got [%s]", s)
+	}
+	return strconv.Itoa(percent), nil
+}
+
+// validPriority checks the failover priority is a non-negative integer, lower value is preferred
+func validPriority(s string) (string, error) {
+	priority, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || priority < 0 {
+		return "", fmt.Errorf("priority must be a non-negative integer, got [%s]", s)
+	}
+	return strconv.Itoa(priority), nil
+}
+
+// maxFlagsSize is the max length of serialized feature flags
+const maxFlagsSize = 1024
+
+// validFlags normalizes comma separated feature flags, they are trimmed, deduplicated and sorted,
+// flags must not be empty and the serialized flags must not exceed maxFlagsSize
+func validFlags(s string) (string, error) {
+	seen := make(map[string]bool)
+	flags := make([]string, 0)
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			return "", fmt.Errorf("feature flag must not be empty, got [%s]", s)
+		}
+		if !seen[f] {
+			seen[f] = true
+			flags = append(flags, f)
+		}
+	}
+	sort.Strings(flags)
+	serialized := strings.Join(flags, ",")
+	if len(serialized) > maxFlagsSize {
+		return "", fmt.Errorf("feature flags size %d exceeds %d", len(serialized), maxFlagsSize)
+	}
+	return serialized, nil
+}
@@ -0,0 +1,289 @@
+package registry
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateInstanceMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.Equal(t, ErrInstanceNotRegistered, UpdateInstanceMetadata(map[string]string{"a": "b"}))
+
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"type": "test", "flag": "off"}
+	runtime.ServiceID = "sid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"flag": "on", "new": "1"}))
+	assert.Equal(t, "on", r.properties["flag"])
+	assert.Equal(t, "1", r.properties["new"])
+	// unrelated keys are preserved
+	assert.Equal(t, "test", r.properties["type"])
+	assert.Contains(t, r.properties, "nodeIP")
+	assert.Equal(t, r.properties, GetSelfMetadata())
+
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"new": "2"}))
+	assert.Equal(t, "on", r.properties["flag"])
+	assert.Equal(t, "2", r.properties["new"])
+}
+
+func TestCapacityMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDCapacity)
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Capacity = 200
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "200", r.instances[1].Metadata[MDCapacity])
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Capacity = -1
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+}
+
+func TestTagsMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.Instance.Tags = []string{" blue", "canary ", "gpu"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "blue,canary,gpu", r.instances[0].Metadata[MDTags])
+
+	for _, tags := range [][]string{{"blue", " "}, {"blue", "blue "}, {"a,b"}} {
+		config.MicroserviceDefinition.ServiceDescription.Instance.Tags = tags
+		assert.Error(t, RegisterMicroserviceInstances(), tags)
+	}
+	assert.Equal(t, 1, len(r.instances))
+}
+
+func TestEncodingsMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDEncodings)
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Encodings = []string{"gzip", " Snappy"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "gzip,snappy", r.instances[1].Metadata[MDEncodings])
+
+	for _, encodings := range [][]string{{"gzip", "lz4"}, {"gzip", "GZIP"}, {""}} {
+		config.MicroserviceDefinition.ServiceDescription.Instance.Encodings = encodings
+		assert.Error(t, RegisterMicroserviceInstances(), encodings)
+	}
+	assert.Equal(t, 2, len(r.instances))
+}
+
+func TestWarmupMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDWarmup)
+
+	ins := &config.MicroserviceDefinition.ServiceDescription.Instance
+	ins.Warmup = "90s"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "1m30s", r.instances[1].Metadata[MDWarmup])
+
+	for _, s := range []string{"90", "-1s", "soon"} {
+		ins.Warmup = s
+		assert.Error(t, RegisterMicroserviceInstances(), s)
+	}
+	assert.Equal(t, 2, len(r.instances))
+	assert.True(t, reservedKeys()[chassisKey(MDWarmup)])
+}
+
+func TestTrafficPercentMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	runtime.ServiceID = "sid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDTrafficPercent)
+
+	for _, p := range []int{0, 100} {
+		percent := p
+		config.MicroserviceDefinition.ServiceDescription.Instance.TrafficPercent = &percent
+		assert.NoError(t, RegisterMicroserviceInstances())
+		assert.Equal(t, strconv.Itoa(p), r.instances[len(r.instances)-1].Metadata[MDTrafficPercent])
+	}
+	for _, p := range []int{-1, 101} {
+		percent := p
+		config.MicroserviceDefinition.ServiceDescription.Instance.TrafficPercent = &percent
+		assert.Error(t, RegisterMicroserviceInstances(), p)
+	}
+	assert.Equal(t, 3, len(r.instances))
+
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{MDTrafficPercent: "20"}))
+	assert.Equal(t, "20", r.properties[MDTrafficPercent])
+	assert.Equal(t, "20", GetSelfMetadata()[MDTrafficPercent])
+	for _, v := range []string{"-1", "101", "half"} {
+		assert.Error(t, UpdateInstanceMetadata(map[string]string{MDTrafficPercent: v}), v)
+	}
+	assert.Equal(t, "20", GetSelfMetadata()[MDTrafficPercent])
+
+	// written as chassis managed key
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.KeyPrefix = "cse."
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{MDTrafficPercent: "30"}))
+	assert.Equal(t, "30", r.properties["cse."+MDTrafficPercent])
+}
+
+func TestSecureMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090"},
+	}
+	restSSL := common.ProtocolRest + "." + common.Provider + "." + common.SslVerifyPeerKey
+	highwaySSL := common.ProtocolHighway + "." + common.Provider + "." + common.SslVerifyPeerKey
+
+	// none TLS
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "false", r.instances[0].Metadata[MDSecure])
+
+	// mixed
+	config.GlobalDefinition.Ssl = map[string]string{restSSL: "false"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "false", r.instances[1].Metadata[MDSecure])
+
+	// all TLS
+	config.GlobalDefinition.Ssl = map[string]string{restSSL: "false", highwaySSL: "false"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "true", r.instances[2].Metadata[MDSecure])
+
+	// plain endpoint advertised alongside ssl endpoint
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1:8080", SSLAdvertise: "127.0.0.1:8443"},
+	}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "false", r.instances[3].Metadata[MDSecure])
+}
+
+func TestNodeIDMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	_, ok := r.instances[0].Metadata[MDNodeID]
+	assert.False(t, ok)
+
+	ins := &config.MicroserviceDefinition.ServiceDescription.Instance
+	ins.NodeID = model.NodeIDStruct{Enabled: true, Env: "TEST_NODE_ID"}
+	os.Setenv("TEST_NODE_ID", "node-1")
+	defer os.Unsetenv("TEST_NODE_ID")
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "node-1", r.instances[1].Metadata[MDNodeID])
+	assert.Equal(t, r.instances[1].Metadata[MDNodeID], r.instances[2].Metadata[MDNodeID])
+
+	ins.NodeID.Value = "node-2"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "node-2", r.instances[3].Metadata[MDNodeID])
+
+	ins.NodeID.Value = ""
+	os.Unsetenv("TEST_NODE_ID")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 4, len(r.instances))
+}
+
+func TestPriorityMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDPriority)
+
+	primary, negative := 0, -1
+	config.MicroserviceDefinition.ServiceDescription.Instance.Priority = &primary
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "0", r.instances[1].Metadata[MDPriority])
+	config.MicroserviceDefinition.ServiceDescription.Instance.Priority = &negative
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+
+	// standby takes over
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{MDPriority: "1"}))
+	assert.Equal(t, "1", GetSelfMetadata()[MDPriority])
+	assert.Error(t, UpdateInstanceMetadata(map[string]string{MDPriority: "first"}))
+}
+
+func TestFlagsMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	config.MicroserviceDefinition.ServiceDescription.Instance.Flags = []string{"newCheckout", " fastPath", "newCheckout"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "fastPath,newCheckout", r.instances[0].Metadata[MDFlags])
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Flags = []string{strings.Repeat("f", maxFlagsSize+1)}
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.instances))
+
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{MDFlags: "fastPath,darkMode"}))
+	assert.Equal(t, "darkMode,fastPath", r.properties[MDFlags])
+	assert.Equal(t, "darkMode,fastPath", GetSelfMetadata()[MDFlags])
+	for _, v := range []string{"a,,b", strings.Repeat("g", maxFlagsSize+1)} {
+		assert.Error(t, UpdateInstanceMetadata(map[string]string{MDFlags: v}))
+	}
+	assert.Equal(t, "darkMode,fastPath", GetSelfMetadata()[MDFlags])
+}
+
+func TestRegionPreferenceMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	config.MicroserviceDefinition.ServiceDescription.Instance.RegionPreference = []string{"cn-north", " cn-east", "cn-south"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "cn-north,cn-east,cn-south", r.instances[0].Metadata[MDRegionPreference])
+
+	for _, regions := range [][]string{{"cn-north", " "}, {"cn-north", "cn-north"}, {"cn-north,cn-east"}} {
+		config.MicroserviceDefinition.ServiceDescription.Instance.RegionPreference = regions
+		assert.Error(t, RegisterMicroserviceInstances(), "%v", regions)
+	}
+	assert.Equal(t, 1, len(r.instances))
+	assert.True(t, reservedKeys()[chassisKey(MDRegionPreference)])
+}
+
+func TestCohortMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	config.MicroserviceDefinition.ServiceDescription.Instance.Cohort = " canary-1 "
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "canary-1", r.instances[0].Metadata[MDCohort])
+	assert.True(t, reservedKeys()[chassisKey(MDCohort)])
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Cohort = "  "
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.instances))
+}
+
+// blockingPropertiesRegistrator blocks instance properties update until released
+type blockingPropertiesRegistrator struct {
+	*fakeRegistrator
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingPropertiesRegistrator) UpdateMicroServiceInstanceProperties(sid, iid string, properties map[string]string) error {
+	b.started <- struct{}{}
+	<-b.release
+	return b.fakeRegistrator.UpdateMicroServiceInstanceProperties(sid, iid, properties)
+}
+
+func TestUpdateInstanceMetadataUnlocked(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	runtime.ServiceID = "sid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	b := &blockingPropertiesRegistrator{fakeRegistrator: r, started: make(chan struct{}), release: make(chan struct{})}
+	DefaultRegistrator = b
+
+	errc := make(chan error)
+	go func() { errc <- UpdateInstanceMetadata(map[string]string{"a": "1"}) }()
+	<-b.started
+	assert.NotContains(t, GetSelfMetadata(), "a", "readers are not blocked by registry")
+	assert.NoError(t, updateSelfMetadata(withEndpointsMetadata(map[string]string{chassisKey(MDSecure): "true"}),
+		func(map[string]string) error { return nil }))
+	b.release <- struct{}{}
+	// changed meanwhile, so it is pushed again on top of the change
+	<-b.started
+	b.release <- struct{}{}
+	assert.NoError(t, <-errc)
+	assert.Equal(t, "1", GetSelfMetadata()["a"])
+	assert.Equal(t, "true", GetSelfMetadata()[chassisKey(MDSecure)])
+	assert.Equal(t, "true", r.properties[chassisKey(MDSecure)])
+}
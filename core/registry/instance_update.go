@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// InstanceUpdater is implemented by registrators which are able to update status and properties of an instance in one transaction
+type InstanceUpdater interface {
+	UpdateMicroServiceInstance(microServiceID, microServiceInstanceID, status string, properties map[string]string) error
+}
+
+// UpdateInstance changes the status of self instance and merges metadataDelta into its metadata together,
+// empty status keeps the current one, metadataDelta follows the rules of UpdateInstanceMetadata,
+// if registrator implements InstanceUpdater both are applied in one transaction,
+// otherwise status is updated first and rolled back if metadata update fails
+func UpdateInstance(status string, metadataDelta map[string]string) error {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	from := runtime.InstanceStatus
+	if status == "" {
+		status = from
+	}
+	if status != from && !canTransit(from, status) {
+		return fmt.Errorf("instance status can not change from %s to %s", from, status)
+	}
+	delta, err := registeredDelta(metadataDelta)
+	if err != nil {
+		return err
+	}
+	err = updateSelfMetadata(func(md, properties map[string]string) (map[string]string, map[string]string) {
+		return mergeMetadata(md, delta), properties
+	}, func(md map[string]string) error {
+		return pushInstance(sid, iid, from, status, md)
+	})
+	if err != nil {
+		return err
+	}
+	if status != from {
+		lager.Logger.Infof("Instance status changed from %s to %s", from, status)
+	}
+	runtime.InstanceStatus = status
+	lager.Logger.Debugf("Update instance success, status %s, delta %v", status, delta)
+	return nil
+}
+
+// pushInstance pushes status and metadata md of self instance sid/iid in status from to registry
+func pushInstance(sid, iid, from, status string, md map[string]string) error {
+	// signed with the target status, as registry holds self instance in it after update
+	encoded, err := registeredSelfMetadata(status, md)
+	if err != nil {
+		return err
+	}
+	if registrationSkipped() {
+		lager.Logger.Debugf("Registration is disabled, instance %s/%s is only updated locally", sid, iid)
+		return nil
+	}
+	if updater, ok := asInstanceUpdater(DefaultRegistrator); ok {
+		if err := updater.UpdateMicroServiceInstance(sid, iid, status, encoded); err != nil {
+			lager.Logger.Errorf("Update instance failed, microServiceID/instanceID = %s/%s: %s", sid, iid, err)
+			return err
+		}
+		return nil
+	}
+	if status != from {
+		if err := DefaultRegistrator.UpdateMicroServiceInstanceStatus(sid, iid, status); err != nil {
+			lager.Logger.Errorf("Update instance status to %s failed: %s", status, err)
+			return err
+		}
+	}
+	if err := updateInstanceProperties(DefaultRegistrator, sid, iid, encoded); err != nil {
+		lager.Logger.Errorf("Update instance metadata failed, microServiceID/instanceID = %s/%s: %s", sid, iid, err)
+		if status != from {
+			if rbErr := DefaultRegistrator.UpdateMicroServiceInstanceStatus(sid, iid, from); rbErr != nil {
+				lager.Logger.Errorf("Roll back instance status to %s failed: %s", from, rbErr)
+				return fmt.Errorf("update instance metadata failed: %s, roll back status to %s failed: %s", err, from, rbErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
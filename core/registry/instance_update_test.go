@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// transactionalRegistrator applies status and properties in one call
+type transactionalRegistrator struct {
+	*fakeRegistrator
+	updates int
+}
+
+func (t *transactionalRegistrator) UpdateMicroServiceInstance(sid, iid, status string, properties map[string]string) error {
+	t.updates++
+	t.status = append(t.status, status)
+	t.properties = properties
+	return t.err
+}
+
+func TestUpdateInstance(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.Equal(t, ErrInstanceNotRegistered, UpdateInstance(runtime.StatusOutOfService, nil))
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, UpdateInstance(runtime.StatusOutOfService, map[string]string{"release": "canary", MDTrafficPercent: "10"}))
+	assert.Equal(t, []string{runtime.StatusOutOfService}, r.status)
+	assert.Equal(t, "canary", r.properties["release"])
+	assert.Equal(t, "10", r.properties[MDTrafficPercent])
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+	assert.Equal(t, "canary", GetSelfMetadata()["release"])
+
+	// invalid delta changes nothing
+	assert.Error(t, UpdateInstance(runtime.StatusRunning, map[string]string{MDTrafficPercent: "101"}))
+	assert.Equal(t, 1, len(r.status))
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+
+	// empty status only updates metadata
+	assert.NoError(t, UpdateInstance("", map[string]string{"release": "stable"}))
+	assert.Equal(t, 1, len(r.status))
+	assert.Equal(t, "stable", r.properties["release"])
+}
+
+func TestUpdateInstanceSignature(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	assert.NoError(t, UpdateInstance(runtime.StatusOutOfService, map[string]string{"release": "canary"}))
+	ins := selfInstance(runtime.InstanceID, GetAdvertisedEndpoints(), r.properties)
+	assert.Equal(t, runtime.StatusOutOfService, ins.Status)
+	assert.Equal(t, unsignedDigest(t, ins, ins.Metadata), r.properties[MDSignature], "signed with the new status")
+}
+
+func TestUpdateInstanceRollback(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	r.propErr = errors.New("registry unavailable")
+
+	err := UpdateInstance(runtime.StatusOutOfService, map[string]string{"release": "canary"})
+	assert.Equal(t, r.propErr, err)
+	assert.Equal(t, []string{runtime.StatusOutOfService, runtime.StatusRunning}, r.status, "status is rolled back")
+	assert.Equal(t, runtime.StatusRunning, runtime.InstanceStatus)
+	assert.NotContains(t, GetSelfMetadata(), "release")
+}
+
+func TestUpdateInstanceTransaction(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	tr := &transactionalRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = tr
+
+	assert.NoError(t, UpdateInstance(runtime.StatusOutOfService, map[string]string{"release": "canary"}))
+	assert.Equal(t, 1, tr.updates)
+	assert.Equal(t, 0, r.propertyUpdates, "properties are not updated separately")
+	assert.Equal(t, "canary", r.properties["release"])
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+
+	r.err = errors.New("conflict")
+	assert.Error(t, UpdateInstance(runtime.StatusRunning, map[string]string{"release": "stable"}))
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+	assert.Equal(t, "canary", GetSelfMetadata()["release"])
+}
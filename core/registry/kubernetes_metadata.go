@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"os"
+
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// metadataProvider returns metadata of self instance gathered from environment,
+// keys are written with key prefix
+type metadataProvider func() map[string]string
+
+// metadataProviders are the built in providers of instance metadata
+var metadataProviders = []metadataProvider{kubernetesMetadata, buildMetadata, chassisVersionMetadata, limitsMetadata}
+
+// conventional env vars populated by kubernetes downward API
+const (
+	defaultPodNameEnv   = "POD_NAME"
+	defaultNamespaceEnv = "POD_NAMESPACE"
+	defaultNodeNameEnv  = "NODE_NAME"
+	defaultPodIPEnv     = "POD_IP"
+)
+
+// kubernetesMetadata reads pod name, namespace, node name and pod ip from downward API env vars,
+// it only takes effect when instance.kubernetes.enabled is true, missing vars are skipped
+func kubernetesMetadata() map[string]string {
+	k8s := config.MicroserviceDefinition.ServiceDescription.Instance.Kubernetes
+	if !k8s.Enabled {
+		return nil
+	}
+	envs := map[string]string{
+		MDPodName:   envName(k8s.PodNameEnv, defaultPodNameEnv),
+		MDNamespace: envName(k8s.NamespaceEnv, defaultNamespaceEnv),
+		MDNodeName:  envName(k8s.NodeNameEnv, defaultNodeNameEnv),
+		MDPodIP:     envName(k8s.PodIPEnv, defaultPodIPEnv),
+	}
+	md := make(map[string]string, len(envs))
+	for k, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+func envName(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
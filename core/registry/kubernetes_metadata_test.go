@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	os.Setenv("POD_NAME", "pod-1")
+	os.Setenv("MY_NAMESPACE", "ns")
+	os.Unsetenv("NODE_NAME")
+	os.Unsetenv("POD_IP")
+	defer func() {
+		os.Unsetenv("POD_NAME")
+		os.Unsetenv("MY_NAMESPACE")
+	}()
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDPodName, "disabled by default")
+
+	k8s := &config.MicroserviceDefinition.ServiceDescription.Instance.Kubernetes
+	k8s.Enabled = true
+	k8s.NamespaceEnv = "MY_NAMESPACE"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md := r.instances[1].Metadata
+	assert.Equal(t, "pod-1", md[MDPodName])
+	assert.Equal(t, "ns", md[MDNamespace])
+	assert.NotContains(t, md, MDNodeName)
+	assert.NotContains(t, md, MDPodIP)
+
+	os.Setenv("NODE_NAME", "node-1")
+	os.Setenv("POD_IP", "10.0.0.3")
+	defer os.Unsetenv("NODE_NAME")
+	defer os.Unsetenv("POD_IP")
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "node-1", r.instances[2].Metadata[MDNodeName])
+	assert.Equal(t, "10.0.0.3", r.instances[2].Metadata[MDPodIP])
+}
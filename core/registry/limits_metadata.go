@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// conventional env vars populated with resource limits by kubernetes downward API
+const (
+	defaultCPULimitEnv    = "CPU_LIMIT"
+	defaultMemoryLimitEnv = "MEMORY_LIMIT"
+)
+
+// cgroupRoot is where cgroup file system is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// unlimitedMemory is the threshold above which cgroup v1 memory limit means no limit
+const unlimitedMemory = 1 << 60
+
+// limitsMetadata reads cpu and memory limits from env vars, or from cgroup if env vars are not set,
+// it only takes effect when instance.limits.enabled is true, missing or unlimited limits are skipped,
+// cpu is in cores and memory is in bytes when they are read from cgroup
+func limitsMetadata() map[string]string {
+	l := config.MicroserviceDefinition.ServiceDescription.Instance.Limits
+	if !l.Enabled {
+		return nil
+	}
+	md := make(map[string]string, 2)
+	if cpu := firstNonEmpty(os.Getenv(envName(l.CPUEnv, defaultCPULimitEnv)), cgroupCPULimit()); cpu != "" {
+		md[MDLimitsCPU] = cpu
+	}
+	if mem := firstNonEmpty(os.Getenv(envName(l.MemoryEnv, defaultMemoryLimitEnv)), cgroupMemoryLimit()); mem != "" {
+		md[MDLimitsMemory] = mem
+	}
+	if len(md) == 0 {
+		lager.Logger.Debug("No cpu or memory limit found")
+	}
+	return md
+}
+
+// cgroupCPULimit returns cpu limit in cores of cgroup v2 cpu.max, or cgroup v1 cfs quota,
+// it returns empty if there is no limit or cgroup is not readable
+func cgroupCPULimit() string {
+	if s, ok := readCgroupFile("cpu.max"); ok {
+		// quota and period, quota is max if unlimited
+		fields := strings.Fields(s)
+		if len(fields) != 2 || fields[0] == "max" {
+			return ""
+		}
+		return cpuCores(fields[0], fields[1])
+	}
+	quota, ok := readCgroupFile(filepath.Join("cpu", "cpu.cfs_quota_us"))
+	if !ok || strings.HasPrefix(quota, "-") {
+		return ""
+	}
+	period, ok := readCgroupFile(filepath.Join("cpu", "cpu.cfs_period_us"))
+	if !ok {
+		return ""
+	}
+	return cpuCores(quota, period)
+}
+
+func cpuCores(quota, period string) string {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return ""
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(q/p, 'f', -1, 64)
+}
+
+// cgroupMemoryLimit returns memory limit in bytes of cgroup v2 memory.max, or cgroup v1 memory.limit_in_bytes,
+// it returns empty if there is no limit or cgroup is not readable
+func cgroupMemoryLimit() string {
+	s, ok := readCgroupFile("memory.max")
+	if !ok {
+		if s, ok = readCgroupFile(filepath.Join("memory", "memory.limit_in_bytes")); !ok {
+			return ""
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n >= unlimitedMemory {
+		return ""
+	}
+	return s
+}
+
+func readCgroupFile(name string) (string, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(cgroupRoot, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
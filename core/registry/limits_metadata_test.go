@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCgroupFiles(t *testing.T, files map[string]string) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+		assert.NoError(t, ioutil.WriteFile(p, []byte(content+"\n"), 0600))
+	}
+	cgroupRoot = dir
+}
+
+func TestLimitsMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	writeCgroupFiles(t, map[string]string{"cpu.max": "150000 100000", "memory.max": "536870912"})
+	defer os.RemoveAll(cgroupRoot)
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDLimitsCPU, "disabled by default")
+
+	config.MicroserviceDefinition.ServiceDescription.Instance.Limits.Enabled = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "1.5", r.instances[1].Metadata[MDLimitsCPU])
+	assert.Equal(t, "536870912", r.instances[1].Metadata[MDLimitsMemory])
+
+	// env takes precedence
+	os.Setenv("CPU_LIMIT", "2")
+	defer os.Unsetenv("CPU_LIMIT")
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "2", r.instances[2].Metadata[MDLimitsCPU])
+}
+
+func TestCgroupLimits(t *testing.T) {
+	defer func(root string) { cgroupRoot = root }(cgroupRoot)
+	writeCgroupFiles(t, map[string]string{
+		"cpu/cpu.cfs_quota_us":         "50000",
+		"cpu/cpu.cfs_period_us":        "100000",
+		"memory/memory.limit_in_bytes": "9223372036854771712",
+	})
+	assert.Equal(t, "0.5", cgroupCPULimit())
+	assert.Equal(t, "", cgroupMemoryLimit(), "unlimited")
+	os.RemoveAll(cgroupRoot)
+
+	writeCgroupFiles(t, map[string]string{"cpu.max": "max 100000", "memory.max": "max"})
+	assert.Equal(t, "", cgroupCPULimit())
+	assert.Equal(t, "", cgroupMemoryLimit())
+	os.RemoveAll(cgroupRoot)
+
+	// absent cgroup
+	initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.Instance.Limits.Enabled = true
+	cgroupRoot = filepath.Join(os.TempDir(), "no-cgroup")
+	assert.Equal(t, "", cgroupCPULimit())
+	assert.Equal(t, "", cgroupMemoryLimit())
+	assert.Empty(t, limitsMetadata())
+}
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// validLocale checks locale is a well formed BCP 47 language tag and returns it in canonical form, e.g. zh-cn becomes zh-CN
+func validLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", fmt.Errorf("locale [%s] is not a valid BCP 47 language tag: %s", locale, err)
+	}
+	return tag.String(), nil
+}
+
+// validTimezone checks tz is an IANA time zone name, Local is rejected since it means different zones on different hosts
+func validTimezone(tz string) (string, error) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" || tz == "Local" {
+		return "", fmt.Errorf("timezone [%s] is not an IANA time zone name", tz)
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", fmt.Errorf("timezone [%s] is not an IANA time zone name: %s", tz, err)
+	}
+	return tz, nil
+}
@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocaleTimezoneMetadata(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	ins := &config.MicroserviceDefinition.ServiceDescription.Instance
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NotContains(t, r.instances[0].Metadata, MDLocale)
+	assert.NotContains(t, r.instances[0].Metadata, MDTimezone)
+
+	ins.Locale, ins.Timezone = "zh-cn", "Asia/Shanghai"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "zh-CN", r.instances[1].Metadata[MDLocale])
+	assert.Equal(t, "Asia/Shanghai", r.instances[1].Metadata[MDTimezone])
+
+	ins.Locale = "not a locale"
+	err := RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid BCP 47 language tag")
+
+	ins.Locale, ins.Timezone = "en-US", "Mars/Olympus"
+	err = RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not an IANA time zone name")
+
+	ins.Timezone = "Local"
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+}
@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// built in encodings of metadata values
+const (
+	// MetadataEncodingPlain sends values as they are, it is the default encoding
+	MetadataEncodingPlain = "plain"
+	// MetadataEncodingBase64 sends values in standard base64
+	MetadataEncodingBase64 = "base64"
+	// MetadataEncodingURL sends values query escaped
+	MetadataEncodingURL = "url"
+)
+
+// MetadataEncoder encodes metadata and property values before they are sent to a registry
+// which does not accept some characters, and decodes them when they are read back,
+// keys and the values registry interprets are never encoded
+type MetadataEncoder interface {
+	Encode(value string) string
+	Decode(value string) (string, error)
+}
+
+type plainEncoder struct{}
+
+func (plainEncoder) Encode(v string) string          { return v }
+func (plainEncoder) Decode(v string) (string, error) { return v, nil }
+
+type base64Encoder struct{}
+
+func (base64Encoder) Encode(v string) string { return base64.StdEncoding.EncodeToString([]byte(v)) }
+func (base64Encoder) Decode(v string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(v)
+	return string(b), err
+}
+
+type urlEncoder struct{}
+
+func (urlEncoder) Encode(v string) string          { return url.QueryEscape(v) }
+func (urlEncoder) Decode(v string) (string, error) { return url.QueryUnescape(v) }
+
+var metadataEncoders = map[string]MetadataEncoder{
+	MetadataEncodingPlain:  plainEncoder{},
+	MetadataEncodingBase64: base64Encoder{},
+	MetadataEncodingURL:    urlEncoder{},
+}
+var metadataEncodersMu sync.RWMutex
+
+// InstallMetadataEncoder installs an encoder of metadata values, it is selected by registrator.metadataEncoding
+func InstallMetadataEncoder(name string, e MetadataEncoder) {
+	metadataEncodersMu.Lock()
+	metadataEncoders[name] = e
+	metadataEncodersMu.Unlock()
+}
+
+// metadataEncoder returns the encoder of registrator.metadataEncoding
+func metadataEncoder() (MetadataEncoder, error) {
+	name := config.GetRegistratorMetadataEncoding()
+	if name == "" {
+		name = MetadataEncodingPlain
+	}
+	metadataEncodersMu.RLock()
+	defer metadataEncodersMu.RUnlock()
+	e, ok := metadataEncoders[name]
+	if !ok {
+		return nil, fmt.Errorf("metadata encoding [%s] is unknown", name)
+	}
+	return e, nil
+}
+
+// interpretedKey returns whether the value of metadata key k is read as it is by registry or chassis,
+// like allowCrossApp, signature, app and version, such values are never encoded
+func interpretedKey(k string) bool {
+	switch k {
+	case chassisKey(MDAllowCrossApp), chassisKey(MDSignature), common.BuildinTagApp, common.BuildinTagVersion:
+		return true
+	}
+	return false
+}
+
+// encodeMetadata returns a copy of md with values encoded as they are sent to registry
+func encodeMetadata(md map[string]string) (map[string]string, error) {
+	e, err := metadataEncoder()
+	if err != nil || md == nil {
+		return md, err
+	}
+	encoded := make(map[string]string, len(md))
+	for k, v := range md {
+		if interpretedKey(k) {
+			encoded[k] = v
+			continue
+		}
+		encoded[k] = e.Encode(v)
+	}
+	return encoded, nil
+}
+
+// DecodeMetadata returns a copy of metadata read back from registry with values decoded by registrator.metadataEncoding
+func DecodeMetadata(md map[string]string) (map[string]string, error) {
+	if md == nil {
+		return nil, nil
+	}
+	e, err := metadataEncoder()
+	if err != nil {
+		return nil, err
+	}
+	decoded := make(map[string]string, len(md))
+	for k, v := range md {
+		if interpretedKey(k) {
+			decoded[k] = v
+			continue
+		}
+		d, err := e.Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("can not decode value of metadata [%s]: %s", k, err)
+		}
+		decoded[k] = d
+	}
+	return decoded, nil
+}
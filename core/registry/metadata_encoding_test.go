@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type reverseEncoder struct{}
+
+func (reverseEncoder) Encode(v string) string {
+	r := []rune(v)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+func (e reverseEncoder) Decode(v string) (string, error) { return e.Encode(v), nil }
+
+func TestMetadataEncoding(t *testing.T) {
+	InstallMetadataEncoder("reverse", reverseEncoder{})
+	defer delete(metadataEncoders, "reverse")
+	special := "a&b=c d/é;%+,"
+	for _, encoding := range []string{"", MetadataEncodingPlain, MetadataEncodingBase64, MetadataEncodingURL, "reverse"} {
+		r, _ := initBootstrapEnv()
+		config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = encoding
+		desc := &config.MicroserviceDefinition.ServiceDescription
+		desc.Deprecated, desc.DeprecationMessage = true, special
+		desc.InstanceProperties = map[string]string{"note": special}
+		assert.NoError(t, RegisterMicroservice(), encoding)
+		assert.NoError(t, RegisterMicroserviceInstances(), encoding)
+
+		sent := r.services[0].Metadata[MDDeprecationMsg]
+		if encoding == "" || encoding == MetadataEncodingPlain {
+			assert.Equal(t, special, sent)
+		} else {
+			assert.NotEqual(t, special, sent, encoding)
+		}
+		md, err := DecodeMetadata(r.services[0].Metadata)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, special, md[MDDeprecationMsg], encoding)
+		md, err = DecodeMetadata(r.properties)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, special, md["note"], encoding)
+		assert.Equal(t, special, GetSelfMetadata()["note"], "self metadata is kept plain")
+
+		assert.NoError(t, UpdateInstanceMetadata(map[string]string{"other": special}), encoding)
+		md, err = DecodeMetadata(r.properties)
+		assert.NoError(t, err, encoding)
+		assert.Equal(t, special, md["other"], encoding)
+	}
+
+	initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = "rot13"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cse.service.registry.registrator.metadataEncoding: metadata encoding [rot13] is unknown")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = MetadataEncodingBase64
+	_, err = DecodeMetadata(map[string]string{"note": "not base64!"})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "[note]"))
+}
+
+func TestInterpretedKeysNotEncoded(t *testing.T) {
+	initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = MetadataEncodingBase64
+	md := map[string]string{"app": "a b", "version": "1.0.0", MDAllowCrossApp: "true", "note": "a b"}
+	encoded, err := encodeMetadata(md)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", encoded["app"])
+	assert.Equal(t, "1.0.0", encoded["version"])
+	assert.Equal(t, "true", encoded[MDAllowCrossApp])
+	assert.NotEqual(t, "a b", encoded["note"])
+	decoded, err := DecodeMetadata(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, md, decoded)
+}
@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultMetadataFileWatchInterval is how often the metadata file is polled if no interval is configured
+const defaultMetadataFileWatchInterval = 10 * time.Second
+
+// ErrMetadataFileWatching means metadata file watcher is already started
+var ErrMetadataFileWatching = errors.New("metadata file watcher is already running")
+
+// DefaultMetadataFileWatcher pushes changes of instance.metadataFile, it is started by DoRegister if the file is configured
+var DefaultMetadataFileWatcher = &MetadataFileWatcher{}
+
+// MetadataFileWatcher polls the metadata file at watchInterval,
+// changed and added keys are pushed to registry by UpdateInstanceMetadata
+type MetadataFileWatcher struct {
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// readMetadataFile parses the flat keys and values of a json or yaml file, nested values are not allowed
+func readMetadataFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read metadata file failed: %s", err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse metadata file [%s] failed: %s", path, err)
+	}
+	md := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch v.(type) {
+		case map[interface{}]interface{}, []interface{}:
+			return nil, fmt.Errorf("value of [%s] in metadata file [%s] must not be nested", k, path)
+		case nil:
+			md[k] = ""
+		default:
+			md[k] = fmt.Sprint(v)
+		}
+	}
+	return md, nil
+}
+
+// fileFacts returns the metadata read from metadata file without keys of instance_properties and updatable chassis keys,
+// which take precedence over it
+func fileFacts(path string) (map[string]string, error) {
+	md, err := readMetadataFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for k := range md {
+		if _, ok := config.MicroserviceDefinition.ServiceDescription.InstanceProperties[k]; ok {
+			delete(md, k)
+		} else if _, ok := updatableKeys[k]; ok {
+			delete(md, k)
+		}
+	}
+	return md, nil
+}
+
+// fileMetadata returns the metadata read from instance.metadataFile with registered keys,
+// chassis managed metadata and instance_properties take precedence over it
+func fileMetadata() (map[string]string, error) {
+	path := config.MicroserviceDefinition.ServiceDescription.Instance.MetadataFile.Path
+	if path == "" {
+		return nil, nil
+	}
+	md, err := fileFacts(path)
+	if err != nil {
+		return nil, err
+	}
+	return checkReservedKeys(userMetadata(md))
+}
+
+// metadataFileWatchInterval returns the configured interval, 10s by default
+func metadataFileWatchInterval() (time.Duration, error) {
+	s := config.MicroserviceDefinition.ServiceDescription.Instance.MetadataFile.WatchInterval
+	if s == "" {
+		return defaultMetadataFileWatchInterval, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("metadata file watch interval is invalid [%s]: %s", s, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("metadata file watch interval must be positive, got [%s]", s)
+	}
+	return d, nil
+}
+
+// Start starts polling the metadata file in background
+func (w *MetadataFileWatcher) Start() error {
+	if registrationSkipped() {
+		lager.Logger.Info("Registration is disabled, metadata file is not watched")
+		return nil
+	}
+	interval, err := metadataFileWatchInterval()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return ErrMetadataFileWatching
+	}
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.run(interval, w.stop, w.done)
+	lager.Logger.Infof("Watch metadata file every %s", interval)
+	return nil
+}
+
+// Stop stops polling the metadata file and waits for the running push to finish
+func (w *MetadataFileWatcher) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (w *MetadataFileWatcher) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := pushMetadataFile(); err != nil {
+				lager.Logger.Warnf("Push metadata file failed: %s", err)
+			}
+		}
+	}
+}
+
+// pushMetadataFile pushes the keys of metadata file whose values differ from self metadata,
+// reserved keys are skipped, keys removed from the file are kept in registry until self instance is registered again
+func pushMetadataFile() error {
+	path := config.MicroserviceDefinition.ServiceDescription.Instance.MetadataFile.Path
+	md, err := fileFacts(path)
+	if err != nil {
+		return err
+	}
+	registered := GetSelfMetadata()
+	reserved := reservedKeys()
+	delta := make(map[string]string)
+	for k, v := range md {
+		if reserved[userKey(k)] {
+			continue
+		}
+		if current, ok := registered[userKey(k)]; !ok || current != v {
+			delta[k] = v
+		}
+	}
+	if len(delta) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(delta))
+	for k := range delta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lager.Logger.Infof("Metadata file [%s] changed, push keys %v", path, keys)
+	return UpdateInstanceMetadata(delta)
+}
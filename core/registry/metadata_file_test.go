@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataFile(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	dir, err := ioutil.TempDir("", "metadata")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "host.yaml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("rack: r1\nrow: 3\nowner: file\nnodeIP: 10.0.0.1\n"), 0600))
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Instance.MetadataFile.Path = path
+	desc.InstanceProperties = map[string]string{"owner": "config"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	md := GetSelfMetadata()
+	assert.Equal(t, "r1", md["rack"])
+	assert.Equal(t, "3", md["row"])
+	assert.Equal(t, "config", md["owner"], "instance properties take precedence")
+	assert.NotEqual(t, "10.0.0.1", md[MDNodeIP], "chassis managed keys take precedence")
+
+	desc.Instance.MetadataFile.WatchInterval = "20ms"
+	updates := r.propertyUpdates
+	w := &MetadataFileWatcher{}
+	assert.NoError(t, w.Start())
+	defer w.Stop()
+	assert.Equal(t, ErrMetadataFileWatching, w.Start())
+	time.Sleep(50 * time.Millisecond)
+	r.mu.Lock()
+	assert.Equal(t, updates, r.propertyUpdates, "nothing pushed if file is not changed")
+	r.mu.Unlock()
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"rack": "r2", "row": 3, "owner": "file", "nodeIP": "10.0.0.1"}`), 0600))
+	time.Sleep(80 * time.Millisecond)
+	r.mu.Lock()
+	assert.Equal(t, "r2", r.properties["rack"])
+	r.mu.Unlock()
+	assert.Equal(t, "r2", GetSelfMetadata()["rack"])
+	assert.Equal(t, "config", GetSelfMetadata()["owner"])
+	w.Stop()
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("rack:\n  name: r3\n"), 0600))
+	assert.Error(t, RegisterMicroserviceInstances(), "nested value")
+	desc.Instance.MetadataFile.Path = filepath.Join(dir, "missing.yaml")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.instances))
+}
@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// metadata keys managed by chassis,
+// they are written with the prefix of cse.service.registry.registrator.keyPrefix
+const (
+	MDAllowCrossApp    = "allowCrossApp"
+	MDDisplayName      = "displayName"
+	MDCategories       = "categories"
+	MDMinClientVersion = "minClientVersion"
+	MDLBStrategy       = "lbStrategy"
+	MDEnvironments     = "environments"
+	MDOwner            = "owner"
+	MDContact          = "contact"
+	MDTier             = "tier"
+	MDDocsURL          = "docsURL"
+	MDDeprecated       = "deprecated"
+	MDDeprecationMsg   = "deprecationMessage"
+	MDSunsetDate       = "sunsetDate"
+	MDMaxConcurrency   = "maxConcurrency"
+	MDRateLimitHint    = "rateLimitHint"
+	MDAuthSchemes      = "authSchemes"
+	MDNodeIP           = "nodeIP"
+	MDStartTime        = "startTime"
+	MDCapacity         = "capacity"
+	MDTags             = "tags"
+	MDEncodings        = "encodings"
+	MDPodName          = "podName"
+	MDNamespace        = "namespace"
+	MDNodeName         = "nodeName"
+	MDPodIP            = "podIP"
+	MDShutdownGrace    = "shutdownGrace"
+	MDWarmup           = "warmup"
+	MDTrafficPercent   = "trafficPercent"
+	MDPriority         = "priority"
+	MDFlags            = "flags"
+	MDCohort           = "cohort"
+	MDLocale           = "locale"
+	MDTimezone         = "timezone"
+	MDRegionPreference = "regionPreference"
+	MDSecure           = "secure"
+	MDSynthetic        = "synthetic"
+	MDNodeID           = "nodeID"
+	MDBasePath         = "basePath"
+	MDHealthPath       = "health"
+	MDAffinity         = "affinity"
+	MDSignature        = "signature"
+	MDLimitsCPU        = "limits.cpu"
+	MDLimitsMemory     = "limits.memory"
+	MDBuildCommit      = "build.commit"
+	MDBuildBranch      = "build.branch"
+	MDBuildTime        = "build.time"
+	MDChassisVersion   = "chassisVersion"
+)
+
+// policies of user metadata using reserved keys
+const (
+	// ReservedKeysOverride drops the user value with a warning, it is the default policy
+	ReservedKeysOverride = "override"
+	// ReservedKeysReject fails the registration
+	ReservedKeysReject = "reject"
+)
+
+// reservedKeys is the set of instance metadata keys user supplied metadata must not use,
+// the metadata keys chassis writes with key prefix, including base and health paths of protocols and affinity hints,
+// and app and version which router and load balancer use as built in tags
+func reservedKeys() map[string]bool {
+	keys := map[string]bool{
+		chassisKey(MDNodeIP):           true,
+		chassisKey(MDStartTime):        true,
+		chassisKey(MDCapacity):         true,
+		chassisKey(MDMaxConcurrency):   true,
+		chassisKey(MDTags):             true,
+		chassisKey(MDEncodings):        true,
+		chassisKey(MDPodName):          true,
+		chassisKey(MDNamespace):        true,
+		chassisKey(MDNodeName):         true,
+		chassisKey(MDPodIP):            true,
+		chassisKey(MDShutdownGrace):    true,
+		chassisKey(MDWarmup):           true,
+		chassisKey(MDTrafficPercent):   true,
+		chassisKey(MDPriority):         true,
+		chassisKey(MDFlags):            true,
+		chassisKey(MDCohort):           true,
+		chassisKey(MDLocale):           true,
+		chassisKey(MDTimezone):         true,
+		chassisKey(MDRegionPreference): true,
+		chassisKey(MDSecure):           true,
+		chassisKey(MDSynthetic):        true,
+		chassisKey(MDSignature):        true,
+		chassisKey(MDNodeID):           true,
+		chassisKey(MDBuildCommit):      true,
+		chassisKey(MDBuildBranch):      true,
+		chassisKey(MDBuildTime):        true,
+		chassisKey(MDChassisVersion):   true,
+		chassisKey(MDLimitsCPU):        true,
+		chassisKey(MDLimitsMemory):     true,
+		common.BuildinTagApp:           true,
+		common.BuildinTagVersion:       true,
+	}
+	for name := range config.GlobalDefinition.Cse.Protocols {
+		keys[chassisKey(basePathKey(name))] = true
+		keys[chassisKey(healthPathKey(name))] = true
+	}
+	for k := range config.MicroserviceDefinition.ServiceDescription.Instance.Affinity {
+		keys[chassisKey(affinityKey(strings.TrimSpace(k)))] = true
+	}
+	return keys
+}
+
+// registryKeys are chassis managed keys interpreted by registry itself, like allowCrossApp granting cross app access,
+// they are prefixed like other chassis keys, registry adapters translate them with RegistryMetadata and ChassisMetadata
+var registryKeys = []string{MDAllowCrossApp}
+
+// chassisKey returns the registered key of a chassis managed key
+func chassisKey(k string) string {
+	return config.GetRegistratorKeyPrefix() + k
+}
+
+// RegistryMetadata returns a copy of md with prefixed registry keys renamed to the bare keys registry interprets,
+// md is returned as it is if no key prefix is configured
+func RegistryMetadata(md map[string]string) map[string]string {
+	return renameKeys(md, chassisKey, func(k string) string { return k })
+}
+
+// ChassisMetadata reverses RegistryMetadata on metadata read back from registry
+func ChassisMetadata(md map[string]string) map[string]string {
+	return renameKeys(md, func(k string) string { return k }, chassisKey)
+}
+
+// renameKeys returns a copy of md with registry keys renamed from their from name to their to name
+func renameKeys(md map[string]string, from, to func(string) string) map[string]string {
+	if md == nil || config.GetRegistratorKeyPrefix() == "" {
+		return md
+	}
+	renamed := make(map[string]string, len(md))
+	for k, v := range md {
+		renamed[k] = v
+	}
+	for _, k := range registryKeys {
+		if v, ok := md[from(k)]; ok {
+			delete(renamed, from(k))
+			renamed[to(k)] = v
+		}
+	}
+	return renamed
+}
+
+// userKey returns the registered key of a user supplied key,
+// it is prefixed only if prefixUserKeys is enabled
+func userKey(k string) string {
+	if config.GetRegistratorPrefixUserKeys() {
+		return config.GetRegistratorKeyPrefix() + k
+	}
+	return k
+}
+
+// userMetadata returns user supplied metadata with registered keys
+func userMetadata(md map[string]string) map[string]string {
+	m := make(map[string]string, len(md))
+	for k, v := range md {
+		m[userKey(k)] = v
+	}
+	return m
+}
+
+// checkReservedKeys handles user metadata colliding with reserved keys according to reservedKeys policy,
+// it returns the user metadata without colliding keys
+func checkReservedKeys(md map[string]string) (map[string]string, error) {
+	policy := config.GetRegistratorReservedKeys()
+	reserved := reservedKeys()
+	m := make(map[string]string, len(md))
+	for k, v := range md {
+		if !reserved[k] {
+			m[k] = v
+			continue
+		}
+		switch policy {
+		case ReservedKeysReject:
+			return nil, fmt.Errorf("metadata key [%s] is reserved by chassis", k)
+		case "", ReservedKeysOverride:
+			lager.Logger.Warnf("metadata key [%s] is reserved by chassis, value [%s] is ignored", k, v)
+		default:
+			return nil, fmt.Errorf("unknown reserved keys policy [%s]", policy)
+		}
+	}
+	return m, nil
+}
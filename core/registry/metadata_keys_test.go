@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	r, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.KeyPrefix = "cse."
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.StartTime = true
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"type": "test"}
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, common.TRUE, r.services[0].Metadata["cse.allowCrossApp"])
+	assert.NotContains(t, r.services[0].Metadata, MDAllowCrossApp)
+	// registry adapters translate the keys interpreted by registry
+	sent := RegistryMetadata(r.services[0].Metadata)
+	assert.Equal(t, common.TRUE, sent[MDAllowCrossApp], "interpreted by registry")
+	assert.NotContains(t, sent, "cse.allowCrossApp")
+	assert.Equal(t, r.services[0].Metadata, ChassisMetadata(sent))
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = true
+	d.services["sid"] = &MicroService{ServiceID: "sid", Metadata: ChassisMetadata(sent)}
+	assert.NoError(t, verifyScope())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = false
+	assert.Contains(t, r.instances[0].Metadata, "cse.nodeIP")
+	assert.Contains(t, r.instances[0].Metadata, "cse.startTime")
+	assert.NotContains(t, r.instances[0].Metadata, MDNodeIP)
+	// user keys are not prefixed by default
+	assert.Equal(t, "test", r.properties["type"])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.PrefixUserKeys = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "test", r.properties["cse.type"])
+	assert.NotContains(t, r.properties, "type")
+}
+
+func TestReservedKeys(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{
+		MDNodeIP: "1.1.1.1",
+		"type":   "test",
+	}
+	config.NodeIP = "10.0.0.1"
+	defer func() { config.NodeIP = "" }()
+
+	// override with warning by default
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "test", r.properties["type"])
+	assert.Equal(t, "10.0.0.1", r.properties[MDNodeIP], "user value is dropped")
+	assert.Equal(t, "10.0.0.1", GetSelfMetadata()[MDNodeIP])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ReservedKeys = ReservedKeysReject
+	err := RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), MDNodeIP)
+	assert.Equal(t, 1, len(r.instances))
+	runtime.ServiceID = "sid"
+	err = UpdateInstanceMetadata(map[string]string{common.BuildinTagVersion: "1.0.0"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), common.BuildinTagVersion)
+}
@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/go-chassis/go-chassis/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// phases of registration
+const (
+	PhaseService  = "service"
+	PhaseInstance = "instance"
+)
+
+// metric names of registration attempts, labeled by phase
+const (
+	MetricRegistrationSuccess = "registration_success_total"
+	MetricRegistrationFailure = "registration_failure_total"
+)
+
+// metricsSink counts registration attempts
+type metricsSink interface {
+	Inc(name, phase string)
+}
+
+// registrationMetrics is the sink of registration counters
+var registrationMetrics metricsSink = &promSink{}
+
+// countRegistration counts an attempt of phase as success or failure by err
+func countRegistration(phase string, err error) {
+	if err != nil {
+		registrationMetrics.Inc(MetricRegistrationFailure, phase)
+		return
+	}
+	registrationMetrics.Inc(MetricRegistrationSuccess, phase)
+}
+
+// promSink registers registration counters to the system prometheus registry
+type promSink struct {
+	once     sync.Once
+	counters map[string]*prometheus.CounterVec
+}
+
+func (s *promSink) init() {
+	s.counters = map[string]*prometheus.CounterVec{
+		MetricRegistrationSuccess: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: MetricRegistrationSuccess,
+			Help: "successful registration attempts",
+		}, []string{"phase"}),
+		MetricRegistrationFailure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: MetricRegistrationFailure,
+			Help: "failed registration attempts",
+		}, []string{"phase"}),
+	}
+	for _, c := range s.counters {
+		metrics.GetSystemPrometheusRegistry().MustRegister(c)
+	}
+}
+
+// Inc increases counter name of phase
+func (s *promSink) Inc(name, phase string) {
+	s.once.Do(s.init)
+	if c, ok := s.counters[name]; ok {
+		c.WithLabelValues(phase).Inc()
+	}
+}
@@ -0,0 +1,52 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSink struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (f *fakeSink) Inc(name, phase string) {
+	f.mu.Lock()
+	f.counts[name+"/"+phase]++
+	f.mu.Unlock()
+}
+
+func TestRegistrationMetrics(t *testing.T) {
+	r, d := initBootstrapEnv()
+	sink := &fakeSink{counts: make(map[string]int)}
+	defer func(s metricsSink) { registrationMetrics = s }(registrationMetrics)
+	registrationMetrics = sink
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	r.sid = ""
+	assert.Error(t, RegisterMicroservice())
+	r.err = errors.New("registry unavailable")
+	assert.Error(t, RegisterMicroservice())
+	assert.Error(t, RegisterMicroserviceInstances())
+	d.err = errors.New("discovery unavailable")
+	assert.Error(t, RegisterMicroserviceInstances())
+
+	assert.Equal(t, map[string]int{
+		MetricRegistrationSuccess + "/" + PhaseService:  1,
+		MetricRegistrationSuccess + "/" + PhaseInstance: 1,
+		MetricRegistrationFailure + "/" + PhaseService:  2,
+		MetricRegistrationFailure + "/" + PhaseInstance: 2,
+	}, sink.counts)
+}
+
+func TestPromSink(t *testing.T) {
+	s := registrationMetrics.(*promSink)
+	s.Inc(MetricRegistrationSuccess, PhaseService)
+	s.Inc(MetricRegistrationFailure, PhaseInstance)
+	s.Inc("unknown", PhaseInstance)
+	assert.Equal(t, 2, len(s.counters))
+}
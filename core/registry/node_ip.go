@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// interfaceIPFunc returns the first non loopback ipv4 address of a network interface
+var interfaceIPFunc = interfaceIP
+
+// nodeIP returns config.NodeIP, if it is empty and detectNodeIP is enabled,
+// the address of nodeIPInterface is used, then the first non loopback address
+func nodeIP() string {
+	if config.NodeIP != "" || !config.GetRegistratorDetectNodeIP() {
+		return config.NodeIP
+	}
+	if name := config.GetRegistratorNodeIPInterface(); name != "" {
+		ip, err := interfaceIPFunc(name)
+		if err == nil {
+			lager.Logger.Infof("Node ip is not set, use [%s] of interface [%s]", ip, name)
+			return ip
+		}
+		lager.Logger.Warnf("Detect node ip from interface [%s] failed: %s", name, err)
+	}
+	ip := localIPFunc()
+	if ip == "" {
+		lager.Logger.Warn("Node ip is not set and no address is detected")
+		return ""
+	}
+	lager.Logger.Infof("Node ip is not set, use detected address [%s]", ip)
+	return ip
+}
+
+func interfaceIP(name string) (string, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ip, _, err := net.ParseCIDR(addr.String())
+		if err == nil && !ip.IsLoopback() && ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no ipv4 address on interface [%s]", name)
+}
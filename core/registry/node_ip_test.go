@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/util/iputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeIP(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer func() {
+		config.NodeIP = ""
+		localIPFunc = iputil.GetLocalIP
+		interfaceIPFunc = interfaceIP
+	}()
+	localIPFunc = func() string { return "10.0.0.1" }
+	interfaceIPFunc = func(name string) (string, error) {
+		if name == "eth1" {
+			return "10.0.1.1", nil
+		}
+		return "", errors.New("no such interface")
+	}
+
+	config.NodeIP = "192.168.0.1"
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DetectNodeIP = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "192.168.0.1", r.instances[0].Metadata[MDNodeIP])
+
+	config.NodeIP = ""
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "10.0.0.1", r.instances[1].Metadata[MDNodeIP])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.NodeIPInterface = "eth1"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "10.0.1.1", r.instances[2].Metadata[MDNodeIP])
+
+	// falls back to the first non loopback address
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.NodeIPInterface = "eth9"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "10.0.0.1", r.instances[3].Metadata[MDNodeIP])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DetectNodeIP = false
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "", r.instances[4].Metadata[MDNodeIP])
+}
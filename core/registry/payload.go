@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"encoding/json"
+)
+
+// RegistrationPayload is what registration sends to registry for self micro-service and instance
+type RegistrationPayload struct {
+	MicroService       *MicroService         `json:"microService"`
+	Instance           *MicroServiceInstance `json:"instance"`
+	InstanceProperties map[string]string     `json:"instanceProperties,omitempty"`
+}
+
+// MarshalRegistrationPayload returns the micro-service and instance as json,
+// they are prepared the same way as registration, limited, signed and encoded, but nothing is sent to registry
+func MarshalRegistrationPayload() ([]byte, error) {
+	ms, err := sealedMicroService()
+	if err != nil {
+		return nil, err
+	}
+	ins, _, properties, err := prepareInstance()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&RegistrationPayload{
+		MicroService:       ms,
+		Instance:           ins,
+		InstanceProperties: properties,
+	})
+}
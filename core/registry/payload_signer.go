@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// PayloadSigner signs registration payloads for registries which authenticate them,
+// the signature is sent along with the payload as metadata signature
+type PayloadSigner interface {
+	Sign(payload []byte) (string, error)
+}
+
+var payloadSigner PayloadSigner
+var payloadSignerMu sync.RWMutex
+
+// SetPayloadSigner sets the signer of registration payloads, nil disables signing
+func SetPayloadSigner(s PayloadSigner) {
+	payloadSignerMu.Lock()
+	payloadSigner = s
+	payloadSignerMu.Unlock()
+}
+
+func getPayloadSigner() PayloadSigner {
+	payloadSignerMu.RLock()
+	defer payloadSignerMu.RUnlock()
+	return payloadSigner
+}
+
+// signMicroService signs the json of ms and puts the signature into its metadata
+func signMicroService(ms *MicroService) error {
+	if ms.Metadata == nil {
+		ms.Metadata = make(map[string]string)
+	}
+	return signPayload(ms, ms.Metadata)
+}
+
+// signInstance signs the json of ins and puts the signature into its metadata
+func signInstance(ins *MicroServiceInstance) error {
+	if ins.Metadata == nil {
+		ins.Metadata = make(map[string]string)
+	}
+	return signPayload(ins, ins.Metadata)
+}
+
+// signPayload signs the json of v without signature, and writes the signature into md of v
+func signPayload(v interface{}, md map[string]string) error {
+	s := getPayloadSigner()
+	if s == nil {
+		return nil
+	}
+	delete(md, chassisKey(MDSignature))
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal registration payload failed: %s", err)
+	}
+	signature, err := s.Sign(payload)
+	if err != nil {
+		return fmt.Errorf("sign registration payload failed: %s", err)
+	}
+	md[chassisKey(MDSignature)] = signature
+	return nil
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256Signer signs payloads with their sha256 digest
+type sha256Signer struct {
+	err error
+}
+
+func (s *sha256Signer) Sign(payload []byte) (string, error) {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), s.err
+}
+
+// unsignedDigest returns the digest of v with signature removed from md
+func unsignedDigest(t *testing.T, v interface{}, md map[string]string) string {
+	signature := md[MDSignature]
+	delete(md, MDSignature)
+	defer func() { md[MDSignature] = signature }()
+	payload, err := json.Marshal(v)
+	assert.NoError(t, err)
+	sig, _ := (&sha256Signer{}).Sign(payload)
+	return sig
+}
+
+func TestPayloadSigner(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer SetPayloadSigner(nil)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RequireSignature = true
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requireSignature")
+
+	SetPayloadSigner(&sha256Signer{})
+	assert.NoError(t, RegisterMicroservice())
+	ms := r.services[0]
+	assert.NotEmpty(t, ms.Metadata[MDSignature])
+	assert.Equal(t, unsignedDigest(t, ms, ms.Metadata), ms.Metadata[MDSignature])
+
+	assert.NoError(t, RegisterMicroserviceInstances())
+	ins := r.instances[0]
+	assert.Equal(t, unsignedDigest(t, ins, ins.Metadata), ins.Metadata[MDSignature])
+	assert.NotContains(t, GetSelfMetadata(), MDSignature)
+
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1"}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, r.instances[1].Metadata[MDSignature], r.properties[MDSignature], "signature survives properties push")
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"zone": "z2"}))
+	assert.Equal(t, "z2", r.properties["zone"])
+	assert.NotEmpty(t, r.properties[MDSignature], "metadata update is signed again")
+	assert.NotEqual(t, r.instances[1].Metadata[MDSignature], r.properties[MDSignature])
+
+	SetPayloadSigner(&sha256Signer{err: errors.New("key expired")})
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalRegistrationPayload(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1:8080", Advertise: "10.0.0.2:80"},
+	}
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1"}
+
+	b, err := MarshalRegistrationPayload()
+	assert.NoError(t, err)
+	assert.Empty(t, r.services, "nothing is registered")
+	assert.Empty(t, r.instances, "nothing is registered")
+
+	p := &RegistrationPayload{}
+	assert.NoError(t, json.Unmarshal(b, p))
+	assert.Equal(t, "default:TestService", p.MicroService.Alias)
+	assert.Equal(t, "10.0.0.2:80", p.Instance.EndpointsMap[common.ProtocolRest])
+	assert.Equal(t, "z1", p.InstanceProperties["zone"])
+	assert.Contains(t, string(b), `"Alias":"default:TestService"`)
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, p.MicroService.Alias, r.services[0].Alias)
+	assert.Equal(t, p.Instance.EndpointsMap, r.instances[0].EndpointsMap)
+}
+
+func TestMarshalRegistrationPayloadPrepared(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	SetPayloadSigner(&sha256Signer{})
+	defer SetPayloadSigner(nil)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = MetadataEncodingBase64
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"note": "a b"}
+
+	b, err := MarshalRegistrationPayload()
+	assert.NoError(t, err)
+	p := &RegistrationPayload{}
+	assert.NoError(t, json.Unmarshal(b, p))
+	assert.NotEmpty(t, p.MicroService.Metadata[MDSignature])
+	assert.NotEqual(t, "a b", p.Instance.Metadata["note"], "encoded as sent")
+	md, err := DecodeMetadata(p.Instance.Metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", md["note"])
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, p.MicroService.Metadata, r.services[0].Metadata)
+	assert.Equal(t, p.Instance.Metadata, r.instances[0].Metadata)
+}
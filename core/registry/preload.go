@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/util/tags"
+)
+
+// defaultPreloadTimeout bounds the preload of provider instances if no timeout is configured
+const defaultPreloadTimeout = 3 * time.Second
+
+// declaredDependencies returns the providers of service_description.dependencies and the ones referenced in config
+// as dependencies of consumer, a declared dependency takes precedence over the reference of the same name,
+// providers without version use the latest one
+func declaredDependencies(consumer *MicroService) *MicroServiceDependency {
+	dep := &MicroServiceDependency{Consumer: consumer}
+	declared := make(map[string]bool)
+	for _, d := range config.MicroserviceDefinition.ServiceDescription.Dependencies {
+		declared[d.Name] = true
+		dep.Providers = append(dep.Providers, &MicroService{
+			ServiceName: d.Name,
+			AppID:       firstNonEmpty(d.AppID, consumer.AppID),
+			Version:     firstNonEmpty(d.Version, common.LatestVersion),
+		})
+	}
+	for name, ref := range config.GetRouterReference() {
+		if declared[name] {
+			continue
+		}
+		version := ref.Version
+		if version == "" {
+			version = common.LatestVersion
+		}
+		dep.Providers = append(dep.Providers, &MicroService{
+			ServiceName: name,
+			AppID:       consumer.AppID,
+			Version:     version,
+		})
+	}
+	return dep
+}
+
+// preloadProviders warms up the instance cache of declared providers if preload is enabled,
+// it returns once all providers are loaded or the preload timeout is reached
+func (r *RegistrationRunner) preloadProviders(consumerID string) {
+	preload := config.GetServiceDiscoveryPreload()
+	if !preload.Enabled || r.Discovery == nil || microServiceDependencies == nil || len(microServiceDependencies.Providers) == 0 {
+		return
+	}
+	timeout := defaultPreloadTimeout
+	if preload.Timeout != "" {
+		d, err := time.ParseDuration(preload.Timeout)
+		if err != nil {
+			lager.Logger.Warnf("preload timeout is invalid [%s], %s is used: %s", preload.Timeout, timeout, err)
+		} else {
+			timeout = d
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range microServiceDependencies.Providers {
+		wg.Add(1)
+		go func(p *MicroService) {
+			defer wg.Done()
+			_, err := r.Discovery.FindMicroServiceInstances(consumerID, p.ServiceName, utiltags.NewDefaultTag(p.Version, p.AppID))
+			if err != nil {
+				lager.Logger.Warnf("Preload instances of [%s] failed: %s", p.ServiceName, err)
+			}
+		}(p)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		lager.Logger.Infof("Preload instances of %d providers success", len(microServiceDependencies.Providers))
+	case <-time.After(timeout):
+		lager.Logger.Warnf("Preload instances of providers timeout after %s", timeout)
+	}
+}
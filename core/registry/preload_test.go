@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreloadProviders(t *testing.T) {
+	_, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.References = map[string]model.ReferencesStruct{
+		"Server":  {Version: "1.0.0"},
+		"Another": {},
+	}
+	assert.NoError(t, RegisterMicroservice())
+
+	// disabled by default
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Empty(t, d.found)
+
+	config.GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.Preload.Enabled = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	sort.Strings(d.found)
+	assert.Equal(t, []string{"Another:latest", "Server:1.0.0"}, d.found)
+}
+
+func TestPreloadProvidersTimeout(t *testing.T) {
+	_, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.References = map[string]model.ReferencesStruct{"Server": {}}
+	config.GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.Preload = model.PreloadStruct{
+		Enabled: true,
+		Timeout: "10ms",
+	}
+	d.findDelay = time.Second
+	assert.NoError(t, RegisterMicroservice())
+	start := time.Now()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.True(t, time.Since(start) < d.findDelay)
+}
@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-archaius/core"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// InstancePropertiesKey matches the config keys of instance properties
+const InstancePropertiesKey = "^service_description\\.instance_properties\\."
+
+const instancePropertiesPrefix = "service_description.instance_properties."
+
+// propertiesDebounce is how long changes of instance properties are gathered before they are pushed
+var propertiesDebounce = time.Second
+
+var propertiesTimer *time.Timer
+var propertiesMu sync.Mutex
+
+// InstancePropertiesListener pushes instance properties changed at runtime to registry
+type InstancePropertiesListener struct{}
+
+// Event applies the change to instance properties of self micro-service,
+// and pushes them to registry once no more change comes within the debounce time
+func (l *InstancePropertiesListener) Event(e *core.Event) {
+	key := strings.TrimPrefix(e.Key, instancePropertiesPrefix)
+	if key == e.Key || key == "" {
+		return
+	}
+	lager.Logger.Debugf("Instance properties event, key: %s, type: %s", key, e.EventType)
+	propertiesMu.Lock()
+	defer propertiesMu.Unlock()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	if desc.InstanceProperties == nil {
+		desc.InstanceProperties = make(map[string]string)
+	}
+	if e.EventType == core.Delete {
+		delete(desc.InstanceProperties, key)
+	} else {
+		desc.InstanceProperties[key] = fmt.Sprint(e.Value)
+	}
+	if propertiesTimer != nil {
+		propertiesTimer.Stop()
+	}
+	propertiesTimer = time.AfterFunc(propertiesDebounce, func() {
+		if err := pushInstanceProperties(); err != nil {
+			lager.Logger.Errorf("Push instance properties failed: %s", err)
+		}
+	})
+}
+
+// pushInstanceProperties replaces the instance properties in metadata of self instance with the configured ones,
+// chassis managed keys and keys updated by UpdateInstanceMetadata are kept
+func pushInstanceProperties() error {
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	propertiesMu.Lock()
+	properties, err := checkReservedKeys(userMetadata(config.MicroserviceDefinition.ServiceDescription.InstanceProperties))
+	propertiesMu.Unlock()
+	if err != nil {
+		return err
+	}
+	err = updateSelfMetadata(func(md, previous map[string]string) (map[string]string, map[string]string) {
+		for k := range previous {
+			delete(md, k)
+		}
+		for k, v := range properties {
+			md[k] = v
+		}
+		return md, properties
+	}, func(md map[string]string) error {
+		encoded, err := registeredSelfMetadata("", md)
+		if err != nil {
+			return err
+		}
+		return updateInstanceProperties(DefaultRegistrator, sid, iid, encoded)
+	})
+	if err != nil {
+		return err
+	}
+	lager.Logger.Infof("Push instance properties success, properties %v", properties)
+	return nil
+}
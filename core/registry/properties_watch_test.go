@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-archaius/core"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func propertyUpdates(r *fakeRegistrator) (int, map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.propertyUpdates, copyMetadata(r.properties)
+}
+
+func TestInstancePropertiesListener(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer func(d time.Duration) { propertiesDebounce = d }(propertiesDebounce)
+	propertiesDebounce = 20 * time.Millisecond
+	config.MicroserviceDefinition.ServiceDescription.InstanceProperties = map[string]string{"zone": "z1", "old": "1"}
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"runtime": "on"}))
+	n, _ := propertyUpdates(r)
+
+	l := &InstancePropertiesListener{}
+	l.Event(&core.Event{EventType: core.Update, Key: "service_description.instance_properties.zone", Value: "z2"})
+	l.Event(&core.Event{EventType: core.Create, Key: "service_description.instance_properties.rack", Value: 3})
+	l.Event(&core.Event{EventType: core.Delete, Key: "service_description.instance_properties.old"})
+	l.Event(&core.Event{EventType: core.Update, Key: "cse.loadbalance.strategy.name", Value: "Random"})
+	time.Sleep(100 * time.Millisecond)
+
+	updates, properties := propertyUpdates(r)
+	assert.Equal(t, n+1, updates, "changes are debounced")
+	assert.Equal(t, "z2", properties["zone"])
+	assert.Equal(t, "3", properties["rack"])
+	assert.Equal(t, "on", properties["runtime"])
+	assert.Equal(t, config.NodeIP, properties[MDNodeIP])
+	_, ok := properties["old"]
+	assert.False(t, ok)
+	assert.Equal(t, properties, GetSelfMetadata())
+}
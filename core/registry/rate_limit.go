@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"go.uber.org/ratelimit"
+)
+
+// registrationLimiter throttles registrator operations of this process, retries included
+var registrationLimiter = &rateLimiter{}
+
+// rateLimiter spaces calls according to cse.service.registry.registrator.rateLimit,
+// the limiter is rebuilt when the rate changes
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    int
+	limiter ratelimit.Limiter
+}
+
+// take blocks until the next operation is allowed, it returns at once if there is no limit
+func (l *rateLimiter) take() {
+	rate := config.GetRegistratorRateLimit()
+	if rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	if l.limiter == nil || l.rate != rate {
+		l.rate = rate
+		l.limiter = ratelimit.New(rate)
+	}
+	limiter := l.limiter
+	l.mu.Unlock()
+	limiter.Take()
+}
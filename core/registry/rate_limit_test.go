@@ -0,0 +1,27 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationRateLimit(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, RegisterMicroserviceInstances())
+	}
+	assert.True(t, time.Since(start) < 50*time.Millisecond, "no limit by default")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RateLimit = 20
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, RegisterMicroserviceInstances())
+	}
+	// calls are spaced by 50ms
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+	assert.Equal(t, 6, len(r.instances))
+}
@@ -0,0 +1,28 @@
+package registry
+
+import (
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// orders of registering self micro-service and instance
+const (
+	// RegistrationOrderServiceFirst registers micro-service, then instance with the service id, it is the default order
+	RegistrationOrderServiceFirst = "serviceFirst"
+	// RegistrationOrderInstanceFirst registers instance in one call which creates micro-service implicitly,
+	// it falls back to service first if the registrator does not support it
+	RegistrationOrderInstanceFirst = "instanceFirst"
+)
+
+// InstanceFirstRegistrator is implemented by registrators which are able to register an instance
+// creating its micro-service implicitly, it returns the service id and the instance id
+type InstanceFirstRegistrator interface {
+	RegisterInstanceFirst(microService *MicroService, instance *MicroServiceInstance) (string, string, error)
+}
+
+// instanceFirst tells whether self instance is registered first with the registrator of r
+func (r *RegistrationRunner) instanceFirst() (InstanceFirstRegistrator, bool) {
+	if config.GetRegistratorRegistrationOrder() != RegistrationOrderInstanceFirst {
+		return nil, false
+	}
+	return asInstanceFirstRegistrator(r.Registrator)
+}
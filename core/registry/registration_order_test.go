@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+// instanceFirstRegistrator creates micro-service along with instance
+type instanceFirstRegistrator struct {
+	*fakeRegistrator
+	calls int
+}
+
+func (f *instanceFirstRegistrator) RegisterInstanceFirst(ms *MicroService, ins *MicroServiceInstance) (string, string, error) {
+	f.calls++
+	f.services = append(f.services, ms)
+	f.instances = append(f.instances, ins)
+	return "implicit-sid", f.iid, f.err
+}
+
+func TestRegistrationOrder(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	loadTestSchemas(t)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder = RegistrationOrderInstanceFirst
+	// falls back to service first
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 1, len(r.services))
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "sid", runtime.ServiceID)
+	assert.Equal(t, "iid", runtime.InstanceID)
+
+	r, _ = initBootstrapEnv()
+	loadTestSchemas(t)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder = RegistrationOrderInstanceFirst
+	first := &instanceFirstRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = first
+	assert.Contains(t, Capabilities(first), CapabilityInstanceFirst)
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 0, len(r.services))
+	assert.Equal(t, "", runtime.ServiceID)
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, "TestService", r.services[0].ServiceName)
+	assert.Equal(t, "implicit-sid", runtime.ServiceID)
+	assert.Equal(t, "iid", runtime.InstanceID)
+	_, ok := r.schemas["s1"]
+	assert.True(t, ok, "schemas are uploaded after instance first registration")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder = RegistrationOrderServiceFirst
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, first.calls)
+	assert.Equal(t, 2, len(r.services))
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder = "random"
+	assert.Error(t, ValidateRegistrationConfig())
+}
+
+func TestVerifyScopeInstanceFirst(t *testing.T) {
+	r, d := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = true
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegistrationOrder = RegistrationOrderInstanceFirst
+	DefaultRegistrator = &instanceFirstRegistrator{fakeRegistrator: r}
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, ErrCrossAppNotAccepted, RegisterMicroserviceInstances(), "verified once the implicit service is known")
+
+	d.services["implicit-sid"] = &MicroService{ServiceID: "implicit-sid", Metadata: map[string]string{MDAllowCrossApp: common.TRUE}}
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "implicit-sid", runtime.ServiceID)
+}
@@ -0,0 +1,32 @@
+package registry
+
+import (
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// names of registration spans
+const (
+	SpanRegisterService  = "register-service"
+	SpanAddSchemas       = "add-schemas"
+	SpanRegisterInstance = "register-instance"
+)
+
+// traceRegistration runs f in a span of the global tracer if tracing is enabled,
+// the error of f is recorded on the span
+func traceRegistration(name string, f func() error) error {
+	if !config.GetRegistratorTracing() {
+		return f()
+	}
+	span := opentracing.StartSpan(name)
+	defer span.Finish()
+	span.SetTag("service", config.MicroserviceDefinition.ServiceDescription.Name)
+	err := f()
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(log.Error(err))
+	}
+	return err
+}
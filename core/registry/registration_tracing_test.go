@@ -0,0 +1,43 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrationTracing(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+	loadTestSchemas(t)
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Empty(t, tracer.FinishedSpans(), "not traced by default")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Tracing = true
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	spans := tracer.FinishedSpans()
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.OperationName)
+		assert.Nil(t, s.Tag("error"))
+		assert.Equal(t, "TestService", s.Tag("service"))
+	}
+	assert.Equal(t, []string{SpanRegisterService, SpanAddSchemas, SpanRegisterInstance}, names)
+
+	tracer.Reset()
+	r.err = errors.New("registry is down")
+	assert.Error(t, RegisterMicroserviceInstances())
+	spans = tracer.FinishedSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, SpanRegisterInstance, spans[0].OperationName)
+	assert.Equal(t, true, spans[0].Tag("error"))
+	assert.Equal(t, "registry is down", spans[0].Logs()[0].Fields[0].ValueString)
+}
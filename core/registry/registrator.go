@@ -55,6 +55,8 @@ type Registrator interface {
 	UnRegisterMicroServiceInstance(microServiceID, microServiceInstanceID string) error
 	UpdateMicroServiceInstanceStatus(microServiceID, microServiceInstanceID, status string) error
 	UpdateMicroServiceProperties(microServiceID string, properties map[string]string) error
+	//UpdateMicroServiceInstanceProperties replaces the properties of an instance,
+	//a registrator unable to update them returns ErrPropertiesUnsupported
 	UpdateMicroServiceInstanceProperties(microServiceID, microServiceInstanceID string, properties map[string]string) error
 	AddSchemas(microServiceID, schemaName, schemaInfo string) error
 }
@@ -73,10 +75,14 @@ func enableRegistrator(opts Options) error {
 	if err != nil {
 		return err
 	}
+	lager.Logger.Debugf("Registrator %s supports %v", rt, Capabilities(DefaultRegistrator))
 
 	if err := RegisterMicroservice(); err != nil {
 		lager.Logger.Errorf("start backoff for register microservice: %s", err)
-		startBackOff(RegisterMicroservice)
+		if err := startBackOff(RegisterMicroservice); err != nil {
+			registrationCompleted(err)
+			return err
+		}
 	}
 	go HBService.Start()
 
@@ -159,10 +165,11 @@ func Enable() (err error) {
 	}
 
 	enableRegistryCache()
-	if err := enableRegistrator(oR); err != nil {
+	// discovery is enabled first so that registration is able to read back what it registers
+	if err := enableServiceDiscovery(oSD); err != nil {
 		return err
 	}
-	if err := enableServiceDiscovery(oSD); err != nil {
+	if err := enableRegistrator(oR); err != nil {
 		return err
 	}
 	enableContractDiscovery(oCD)
@@ -189,14 +196,39 @@ func DoRegister() error {
 		{
 			tmpErr := fmt.Errorf("parameter incorrect, autoregister: %s", t)
 			lager.Logger.Error(tmpErr.Error())
+			registrationCompleted(tmpErr)
 			return tmpErr
 		}
 	}
+	registrationBudget.reset()
 	if isAutoRegister {
 		if err := RegisterMicroserviceInstances(); err != nil {
 			lager.Logger.Errorf("start back off for register microservice instances background: %s", err)
-			go startBackOff(RegisterMicroserviceInstances)
+			go func() {
+				err := startBackOff(RegisterMicroserviceInstances)
+				if err == nil {
+					startInstanceTasks()
+				}
+				registrationCompleted(err)
+			}()
+			return nil
 		}
+		startInstanceTasks()
+		registrationCompleted(nil)
 	}
 	return nil
 }
+
+// startInstanceTasks starts the heartbeat and metadata file watcher of self instance once it is registered
+func startInstanceTasks() {
+	if config.GetRegistratorHeartbeatInterval() != "" {
+		if err := DefaultInstanceHeartbeat.Start(); err != nil {
+			lager.Logger.Warnf("start instance heartbeat failed: %s", err)
+		}
+	}
+	if config.MicroserviceDefinition.ServiceDescription.Instance.MetadataFile.Path != "" {
+		if err := DefaultMetadataFileWatcher.Start(); err != nil {
+			lager.Logger.Warnf("start metadata file watcher failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// registrationBudget is the retry budget shared by the registration operations of one round,
+// it is reset once registration succeeds and at each DoRegister, so that a later round retries again
+var registrationBudget = &retryBudget{}
+
+// retryBudget limits the total time spent waiting for retries,
+// the limit is read from cse.service.registry.registrator.retryBudget, empty means no limit
+type retryBudget struct {
+	mu    sync.Mutex
+	spent time.Duration
+}
+
+func retryBudgetLimit() time.Duration {
+	s := config.GetRegistratorRetryBudget()
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		lager.Logger.Warnf("invalid retry budget [%s], retry without budget: %s", s, err)
+		return 0
+	}
+	return d
+}
+
+// take spends d from the budget, it returns the wait allowed by the remaining budget
+// or backoff.Stop if the budget is exhausted
+func (b *retryBudget) take(d time.Duration) time.Duration {
+	limit := retryBudgetLimit()
+	if limit <= 0 {
+		return d
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	remaining := limit - b.spent
+	if remaining <= 0 {
+		return backoff.Stop
+	}
+	if d > remaining {
+		d = remaining
+	}
+	b.spent += d
+	return d
+}
+
+// reset gives back the whole budget
+func (b *retryBudget) reset() {
+	b.mu.Lock()
+	b.spent = 0
+	b.mu.Unlock()
+}
+
+func (b *retryBudget) exhausted() bool {
+	limit := retryBudgetLimit()
+	if limit <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent >= limit
+}
+
+// budgetBackOff is a back off which stops when retry budget is exhausted
+type budgetBackOff struct {
+	backoff.BackOff
+	budget *retryBudget
+}
+
+// NextBackOff returns the next wait, limited by the retry budget
+func (b *budgetBackOff) NextBackOff() time.Duration {
+	d := b.BackOff.NextBackOff()
+	if d == backoff.Stop {
+		return d
+	}
+	return b.budget.take(d)
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget(t *testing.T) {
+	initBootstrapEnv()
+	registrationBudget = &retryBudget{}
+	defer func() { registrationBudget = &retryBudget{} }()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "20ms"
+
+	var first, second int
+	errFail := errors.New("registry unavailable")
+	err := startBackOff(func() error {
+		first++
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 2, first, "retried once until budget is spent")
+	assert.True(t, registrationBudget.exhausted())
+
+	err = startBackOff(func() error {
+		second++
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 1, second, "no retry after budget is spent")
+
+	assert.NoError(t, startBackOff(func() error { return nil }))
+	assert.False(t, registrationBudget.exhausted(), "budget is reset once registration succeeds")
+
+	// a later round retries again
+	var third int
+	err = startBackOff(func() error {
+		third++
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, 2, third)
+	assert.True(t, registrationBudget.exhausted())
+
+	resetCompletion()
+	defer resetCompletion()
+	assert.NoError(t, DoRegister())
+	assert.False(t, registrationBudget.exhausted(), "budget is reset at each DoRegister")
+}
@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/cenkalti/backoff"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// RetryClassifier tells whether a failed registration is worth retrying,
+// it returns false for failures which will never succeed by retrying, like an invalid payload
+type RetryClassifier func(err error) bool
+
+var retryClassifier RetryClassifier = DefaultRetryClassifier
+var retryClassifierMu sync.RWMutex
+
+// SetRetryClassifier sets the classifier used by every registration retry, nil restores DefaultRetryClassifier
+func SetRetryClassifier(c RetryClassifier) {
+	if c == nil {
+		c = DefaultRetryClassifier
+	}
+	retryClassifierMu.Lock()
+	retryClassifier = c
+	retryClassifierMu.Unlock()
+}
+
+func getRetryClassifier() RetryClassifier {
+	retryClassifierMu.RLock()
+	defer retryClassifierMu.RUnlock()
+	return retryClassifier
+}
+
+// nonRetryableMessages are fragments of registry responses which reject the request itself
+var nonRetryableMessages = []string{
+	"invalid parameter",
+	"bad request",
+	"unauthorized",
+	"forbidden",
+}
+
+// DefaultRetryClassifier retries network failures and unknown errors,
+// invalid configuration, rejected requests and the errors of this package which never change on retry are not retried
+func DefaultRetryClassifier(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch e := err.(type) {
+	case ValidationError, *FieldError:
+		return false
+	case net.Error:
+		return true
+	default:
+		if e == ErrCrossAppNotAccepted {
+			return false
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range nonRetryableMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// retryable reports whether err should be retried according to the configured classifier
+func retryable(err error) bool {
+	return getRetryClassifier()(err)
+}
+
+// classifiedOperation stops backoff retrying operation once it returns a non-retryable error
+func classifiedOperation(operation func() error) func() error {
+	return func() error {
+		err := operation()
+		if err != nil && !retryable(err) {
+			lager.Logger.Errorf("registration failed with non-retryable error, give up: %s", err)
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+}
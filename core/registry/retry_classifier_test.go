@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRetryClassifier(t *testing.T) {
+	assert.False(t, DefaultRetryClassifier(nil))
+	assert.False(t, DefaultRetryClassifier(ValidationError{{Field: "service_description.name", Message: "is empty"}}))
+	assert.False(t, DefaultRetryClassifier(&FieldError{Field: "service_description.name", Message: "is empty"}))
+	assert.False(t, DefaultRetryClassifier(ErrCrossAppNotAccepted))
+	assert.False(t, DefaultRetryClassifier(errors.New("register micro service failed: 400 Bad Request")))
+
+	assert.True(t, DefaultRetryClassifier(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.True(t, DefaultRetryClassifier(ErrRegistrationCircuitOpen))
+	assert.True(t, DefaultRetryClassifier(errors.New("registry unavailable")))
+}
+
+func TestStartBackOffNonRetryable(t *testing.T) {
+	initBootstrapEnv()
+	registrationBudget = &retryBudget{}
+	defer func() { registrationBudget = &retryBudget{} }()
+
+	var n int
+	errInvalid := ValidationError{{Field: "service_description.name", Message: "is empty"}}
+	err := startBackOff(func() error {
+		n++
+		return errInvalid
+	})
+	assert.Equal(t, errInvalid, err)
+	assert.Equal(t, 1, n, "non-retryable error is not retried")
+
+	errCustom := errors.New("quota exceeded")
+	SetRetryClassifier(func(err error) bool { return err != errCustom })
+	defer SetRetryClassifier(nil)
+	n = 0
+	err = startBackOff(func() error {
+		n++
+		return errCustom
+	})
+	assert.Equal(t, errCustom, err)
+	assert.Equal(t, 1, n, "custom classifier is honored")
+
+	SetRetryClassifier(nil)
+	assert.True(t, getRetryClassifier()(errCustom), "nil restores default classifier")
+}
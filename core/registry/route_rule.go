@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// RouteRulePublisher is implemented by registrators which store route rules along with micro-service
+type RouteRulePublisher interface {
+	PutRouteRule(microServiceID string, rules []*model.RouteRule) error
+}
+
+// routeRuleProblems checks the structure of route rules,
+// each rule needs routes with tags, and weights between 0 and 100 in total
+func routeRuleProblems(rules []*model.RouteRule) []*FieldError {
+	var problems []*FieldError
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	for i, rule := range rules {
+		field := fmt.Sprintf("service_description.routeRules[%d]", i)
+		if rule == nil {
+			add(field, "route rule is empty")
+			continue
+		}
+		if rule.Precedence < 0 {
+			add(field+".precedence", "precedence %d is negative", rule.Precedence)
+		}
+		if len(rule.Routes) == 0 {
+			add(field+".route", "route rule has no route")
+			continue
+		}
+		total := 0
+		for j, route := range rule.Routes {
+			routeField := fmt.Sprintf("%s.route[%d]", field, j)
+			if route == nil || len(route.Tags) == 0 {
+				add(routeField+".tags", "route has no tags")
+				continue
+			}
+			if route.Weight < 0 || route.Weight > 100 {
+				add(routeField+".weight", "weight %d is not between 0 and 100", route.Weight)
+				continue
+			}
+			total += route.Weight
+		}
+		if total > 100 {
+			add(field+".route", "total weight %d exceeds 100", total)
+		}
+	}
+	return problems
+}
+
+// publishRouteRules puts route rules of self micro-service sid to registry,
+// it only takes effect when publishRouteRules is enabled and registrator implements RouteRulePublisher
+func (r *RegistrationRunner) publishRouteRules(sid string, rules []*model.RouteRule) error {
+	if !config.GetRegistratorPublishRouteRules() || len(rules) == 0 {
+		return nil
+	}
+	publisher, ok := asRouteRulePublisher(r.Registrator)
+	if !ok {
+		lager.Logger.Warnf("Registrator can not store route rules, %d rules are not published", len(rules))
+		return nil
+	}
+	if err := publisher.PutRouteRule(sid, rules); err != nil {
+		lager.Logger.Errorf("Publish route rules of [%s] failed: %s", sid, err)
+		return err
+	}
+	lager.Logger.Infof("Publish %d route rules of [%s] success", len(rules), sid)
+	return nil
+}
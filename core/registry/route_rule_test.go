@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRouteRulePublisher stores route rules besides registering
+type fakeRouteRulePublisher struct {
+	*fakeRegistrator
+	rules map[string][]*model.RouteRule
+}
+
+func (f *fakeRouteRulePublisher) PutRouteRule(microServiceID string, rules []*model.RouteRule) error {
+	f.rules[microServiceID] = rules
+	return nil
+}
+
+func TestPublishRouteRules(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	p := &fakeRouteRulePublisher{fakeRegistrator: r, rules: make(map[string][]*model.RouteRule)}
+	DefaultRegistrator = p
+	rules := []*model.RouteRule{{
+		Precedence: 1,
+		Routes: []*model.RouteTag{
+			{Tags: map[string]string{"version": "1.0"}, Weight: 80},
+			{Tags: map[string]string{"version": "2.0"}, Weight: 20},
+		},
+	}}
+	config.MicroserviceDefinition.ServiceDescription.RouteRules = rules
+
+	// disabled by default
+	assert.NoError(t, RegisterMicroservice())
+	assert.Empty(t, p.rules)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.PublishRouteRules = true
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, rules, p.rules[r.sid])
+
+	// registrators not supporting route rules are skipped
+	DefaultRegistrator = r
+	assert.NoError(t, RegisterMicroservice())
+}
+
+func TestInvalidRouteRules(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	p := &fakeRouteRulePublisher{fakeRegistrator: r, rules: make(map[string][]*model.RouteRule)}
+	DefaultRegistrator = p
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.PublishRouteRules = true
+	config.MicroserviceDefinition.ServiceDescription.RouteRules = []*model.RouteRule{
+		{Routes: []*model.RouteTag{
+			{Tags: map[string]string{"version": "1.0"}, Weight: 80},
+			{Tags: map[string]string{"version": "2.0"}, Weight: 30},
+		}},
+		{Precedence: -1},
+		{Routes: []*model.RouteTag{{Weight: 10}}},
+	}
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	for _, field := range []string{
+		"service_description.routeRules[0].route: total weight 110 exceeds 100",
+		"service_description.routeRules[1].precedence",
+		"service_description.routeRules[1].route",
+		"service_description.routeRules[2].route[0].tags",
+	} {
+		assert.Contains(t, err.Error(), field)
+	}
+	assert.Empty(t, r.services)
+	assert.Empty(t, p.rules)
+}
@@ -0,0 +1,322 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/schema"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"gopkg.in/yaml.v2"
+)
+
+// states of schema upload
+const (
+	SchemaPending = "pending"
+	SchemaReady   = "ready"
+	SchemaFailed  = "failed"
+)
+
+var schemaState = SchemaPending
+var schemaErr error
+var schemaMu sync.RWMutex
+
+// registeredSchemas are the schema ids last uploaded for registeredSchemasSID
+var registeredSchemas []string
+var registeredSchemasSID string
+
+// policies of schemas exceeding maxSchemas
+const (
+	// MaxSchemasFail fails the registration, it is the default policy
+	MaxSchemasFail = "fail"
+	// MaxSchemasTruncate logs a warning and uploads the first maxSchemas schemas in order of schema id
+	MaxSchemasTruncate = "truncate"
+)
+
+// policies of schema contents which can not be parsed, schemas are not validated by default
+const (
+	// ValidateSchemasWarn logs a warning and uploads malformed schemas as they are
+	ValidateSchemasWarn = "warn"
+	// ValidateSchemasFail fails the registration
+	ValidateSchemasFail = "fail"
+)
+
+// policies of schemas with empty content
+const (
+	// EmptySchemasSkip logs a warning and keeps the schema id on micro-service without uploading its content,
+	// it is the default policy
+	EmptySchemasSkip = "skip"
+	// EmptySchemasReject fails the registration
+	EmptySchemasReject = "reject"
+)
+
+// SchemaDeleter is implemented by registrators which are able to delete schemas
+type SchemaDeleter interface {
+	DeleteSchema(microServiceID, schemaID string) error
+}
+
+func setSchemaStatus(state string, err error) {
+	schemaMu.Lock()
+	schemaState = state
+	schemaErr = err
+	schemaMu.Unlock()
+}
+
+// SchemaStatus returns the state of schema upload of self micro-service,
+// and the error if upload failed
+func SchemaStatus() (string, error) {
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	return schemaState, schemaErr
+}
+
+// registerSchemas uploads schema contents of self micro-service,
+// in async mode it returns immediately and uploads them in background
+func (r *RegistrationRunner) registerSchemas(sid string, schemaIDs []string) {
+	setSchemaStatus(SchemaPending, nil)
+	if !config.GetRegistratorAsyncSchemas() {
+		r.uploadSchemas(sid, schemaIDs)
+		return
+	}
+	lager.Logger.Infof("Upload %d schemas in background", len(schemaIDs))
+	go r.uploadSchemas(sid, schemaIDs)
+}
+
+// uploadSchemas uploads schema contents and records the result in schema status,
+// schemas with empty content are not uploaded
+func (r *RegistrationRunner) uploadSchemas(sid string, schemaIDs []string) {
+	schemaIDs, _ = splitEmptySchemas(schemaIDs)
+	if err := traceRegistration(SpanAddSchemas, func() error {
+		return r.addSchemas(sid, schemaIDs)
+	}); err != nil {
+		lager.Logger.Error(err.Error())
+		setSchemaStatus(SchemaFailed, err)
+		return
+	}
+	setSchemaStatus(SchemaReady, nil)
+}
+
+// addSchemas uploads schema contents, they are uploaded in one call if registrator implements SchemaBatchAdder,
+// otherwise by up to schemaConcurrency workers
+func (r *RegistrationRunner) addSchemas(sid string, schemaIDs []string) error {
+	if adder, ok := asSchemaBatchAdder(r.Registrator); ok && len(schemaIDs) != 0 {
+		return r.addSchemasBatch(adder, sid, schemaIDs)
+	}
+	errs := make([]error, len(schemaIDs))
+	workers := config.GetRegistratorSchemaConcurrency()
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(schemaIDs) {
+		workers = len(schemaIDs)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = r.addSchema(sid, schemaIDs[i])
+			}
+		}()
+	}
+	for i := range schemaIDs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	// errors are reported in order of schema ids whatever order uploads finish in
+	var failed, added []string
+	for i, err := range errs {
+		if err != nil {
+			lager.Logger.Warnf("Add schema [%s] failed: %s", schemaIDs[i], err)
+			failed = append(failed, fmt.Sprintf("%s: %s", schemaIDs[i], err))
+			continue
+		}
+		added = append(added, schemaIDs[i])
+	}
+	if len(failed) != 0 {
+		// stale schemas are not deleted until all schemas are uploaded
+		recordAddedSchemas(sid, added)
+		return fmt.Errorf("add schemas failed: %s", strings.Join(failed, "; "))
+	}
+	r.deleteStaleSchemas(sid, schemaIDs)
+	return nil
+}
+
+// addSchema uploads the content of one schema
+func (r *RegistrationRunner) addSchema(sid, schemaID string) error {
+	schemaInfo, err := compressSchema(schema.DefaultSchemaIDsMap[schemaID])
+	if err != nil {
+		return err
+	}
+	if cr, ok := asContextRegistrator(r.Registrator); ok {
+		return callContext(OpAddSchemas, "", func(ctx context.Context) error {
+			return cr.AddSchemasWithContext(ctx, sid, schemaID, schemaInfo)
+		})
+	}
+	return callWithTimeout(OpAddSchemas, func() error {
+		return r.Registrator.AddSchemas(sid, schemaID, schemaInfo)
+	})
+}
+
+// addSchemasBatch uploads schema contents in one call
+func (r *RegistrationRunner) addSchemasBatch(adder SchemaBatchAdder, sid string, schemaIDs []string) error {
+	schemas := make(map[string]string, len(schemaIDs))
+	for _, schemaID := range schemaIDs {
+		content, err := compressSchema(schema.DefaultSchemaIDsMap[schemaID])
+		if err != nil {
+			return fmt.Errorf("compress schema [%s] failed: %s", schemaID, err)
+		}
+		schemas[schemaID] = content
+	}
+	if err := callWithTimeout(OpAddSchemas, func() error {
+		return adder.AddSchemasBatch(sid, schemas)
+	}); err != nil {
+		return fmt.Errorf("add schemas failed: %s", err)
+	}
+	r.deleteStaleSchemas(sid, schemaIDs)
+	return nil
+}
+
+// deleteStaleSchemas deletes schemas uploaded by last registration of sid but no longer defined locally,
+// it only takes effect when deleteStaleSchemas is enabled and registrator implements SchemaDeleter
+func (r *RegistrationRunner) deleteStaleSchemas(sid string, schemaIDs []string) {
+	previous, previousSID := recordSchemas(sid, schemaIDs)
+	if !config.GetRegistratorDeleteStaleSchemas() || previousSID != sid {
+		return
+	}
+	stale := subtract(previous, schemaIDs)
+	if len(stale) == 0 {
+		return
+	}
+	deleter, ok := asSchemaDeleter(r.Registrator)
+	if !ok {
+		lager.Logger.Warnf("Registrator can not delete schemas, stale schemas %v are kept", stale)
+		return
+	}
+	for _, schemaID := range stale {
+		if err := deleter.DeleteSchema(sid, schemaID); err != nil {
+			lager.Logger.Warnf("Delete stale schema [%s] failed: %s", schemaID, err)
+			continue
+		}
+		lager.Logger.Infof("Delete stale schema [%s] success", schemaID)
+	}
+}
+
+// recordSchemas records the schema ids uploaded for sid and returns the previous record
+func recordSchemas(sid string, schemaIDs []string) ([]string, string) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	previous, previousSID := registeredSchemas, registeredSchemasSID
+	registeredSchemas, registeredSchemasSID = schemaIDs, sid
+	return previous, previousSID
+}
+
+// recordAddedSchemas adds the schema ids uploaded for sid to the record, keeping the ones uploaded before
+func recordAddedSchemas(sid string, added []string) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	if registeredSchemasSID != sid {
+		registeredSchemas, registeredSchemasSID = added, sid
+		return
+	}
+	registeredSchemas = append(subtract(registeredSchemas, added), added...)
+}
+
+// limitSchemas applies maxSchemas to schema ids of self micro-service according to maxSchemasPolicy
+func limitSchemas(schemaIDs []string) ([]string, error) {
+	max := config.GetRegistratorMaxSchemas()
+	if max <= 0 || len(schemaIDs) <= max {
+		return schemaIDs, nil
+	}
+	switch policy := config.GetRegistratorMaxSchemasPolicy(); policy {
+	case "", MaxSchemasFail:
+		return nil, fmt.Errorf("found %d schemas, exceeds maxSchemas %d", len(schemaIDs), max)
+	case MaxSchemasTruncate:
+		sorted := make([]string, len(schemaIDs))
+		copy(sorted, schemaIDs)
+		sort.Strings(sorted)
+		lager.Logger.Warnf("Found %d schemas, exceeds maxSchemas %d, schemas %v are not uploaded", len(schemaIDs), max, sorted[max:])
+		return sorted[:max], nil
+	default:
+		return nil, fmt.Errorf("unknown max schemas policy [%s]", policy)
+	}
+}
+
+// splitEmptySchemas splits schema ids in order by whether their content is empty or blank
+func splitEmptySchemas(schemaIDs []string) (nonEmpty, empty []string) {
+	for _, schemaID := range schemaIDs {
+		if strings.TrimSpace(schema.DefaultSchemaIDsMap[schemaID]) == "" {
+			empty = append(empty, schemaID)
+			continue
+		}
+		nonEmpty = append(nonEmpty, schemaID)
+	}
+	return nonEmpty, empty
+}
+
+// checkEmptySchemas handles schemas with empty content according to emptySchemas policy
+func checkEmptySchemas(schemaIDs []string) error {
+	_, empty := splitEmptySchemas(schemaIDs)
+	switch policy := config.GetRegistratorEmptySchemas(); policy {
+	case "", EmptySchemasSkip:
+		if len(empty) != 0 {
+			lager.Logger.Warnf("Content of schemas %v is empty, they are not uploaded", empty)
+		}
+		return nil
+	case EmptySchemasReject:
+		if len(empty) != 0 {
+			return fmt.Errorf("content of schemas %v is empty", empty)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown empty schemas policy [%s]", policy)
+	}
+}
+
+// validateSchemas checks schema contents are parseable OpenAPI documents according to validateSchemas policy,
+// empty contents are left to checkEmptySchemas
+func validateSchemas(schemaIDs []string) error {
+	policy := config.GetRegistratorValidateSchemas()
+	if policy == "" {
+		return nil
+	}
+	if policy != ValidateSchemasWarn && policy != ValidateSchemasFail {
+		return fmt.Errorf("unknown validate schemas policy [%s]", policy)
+	}
+	nonEmpty, _ := splitEmptySchemas(schemaIDs)
+	var malformed []string
+	for _, schemaID := range nonEmpty {
+		if err := parseSchema(schema.DefaultSchemaIDsMap[schemaID]); err != nil {
+			malformed = append(malformed, fmt.Sprintf("%s: %s", schemaID, err))
+		}
+	}
+	if len(malformed) == 0 {
+		return nil
+	}
+	if policy == ValidateSchemasFail {
+		return fmt.Errorf("malformed schemas: %s", strings.Join(malformed, "; "))
+	}
+	lager.Logger.Warnf("Malformed schemas are uploaded as they are: %s", strings.Join(malformed, "; "))
+	return nil
+}
+
+// parseSchema checks content is a yaml or json OpenAPI document declaring its swagger or openapi version
+func parseSchema(content string) error {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return fmt.Errorf("can not parse content: %s", err)
+	}
+	if _, ok := doc["swagger"]; ok {
+		return nil
+	}
+	if _, ok := doc["openapi"]; ok {
+		return nil
+	}
+	return fmt.Errorf("content declares neither swagger nor openapi version")
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// CompressedSchemaPrefix marks schema content uploaded gzip compressed and base64 encoded
+const CompressedSchemaPrefix = "gzip+base64:"
+
+// compressSchema returns the content of a schema as it is uploaded,
+// it is compressed if registrator.schemaCompressThreshold is positive and content is longer than it
+func compressSchema(content string) (string, error) {
+	threshold := config.GetRegistratorSchemaCompressThreshold()
+	if threshold <= 0 || len(content) <= threshold {
+		return content, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return CompressedSchemaPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecompressSchema returns the original content of a schema read back from registry,
+// content without CompressedSchemaPrefix is returned as it is
+func DecompressSchema(content string) (string, error) {
+	if !strings.HasPrefix(content, CompressedSchemaPrefix) {
+		return content, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(content, CompressedSchemaPrefix))
+	if err != nil {
+		return "", fmt.Errorf("can not decode compressed schema: %s", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return "", fmt.Errorf("can not decompress schema: %s", err)
+	}
+	defer r.Close()
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("can not decompress schema: %s", err)
+	}
+	return string(raw), nil
+}
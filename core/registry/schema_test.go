@@ -0,0 +1,288 @@
+package registry
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// loadTestSchemas writes schema files of ids for TestService and loads them, s1 by default
+func loadTestSchemas(t *testing.T, ids ...string) {
+	if len(ids) == 0 {
+		ids = []string{"s1"}
+	}
+	dir, err := ioutil.TempDir("", "schemas")
+	assert.NoError(t, err)
+	schemaDir := filepath.Join(dir, "TestService", "schema")
+	assert.NoError(t, os.MkdirAll(schemaDir, 0700))
+	for _, id := range ids {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(schemaDir, id+".yaml"), []byte("swagger: '2.0'"), 0600))
+	}
+	assert.NoError(t, schema.LoadSchema(dir, true))
+	os.RemoveAll(dir)
+}
+
+func waitSchemaStatus(t *testing.T, state string) error {
+	for i := 0; i < 100; i++ {
+		if s, err := SchemaStatus(); s == state {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s, _ := SchemaStatus()
+	t.Fatalf("schema status is %s, expected %s", s, state)
+	return nil
+}
+
+func TestRegisterSchemas(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	loadTestSchemas(t)
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []string{"s1"}, r.services[0].Schemas)
+	assert.Equal(t, "swagger: '2.0'", r.schemas["s1"])
+	s, err := SchemaStatus()
+	assert.Equal(t, SchemaReady, s)
+	assert.NoError(t, err)
+}
+
+func TestRegisterSchemasAsync(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	loadTestSchemas(t)
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.AsyncSchemas = true
+	r.delay[OpAddSchemas] = 50 * time.Millisecond
+
+	assert.NoError(t, RegisterMicroservice())
+	s, _ := SchemaStatus()
+	assert.Equal(t, SchemaPending, s, "service is registered before schemas")
+	assert.NoError(t, waitSchemaStatus(t, SchemaReady))
+	r.mu.Lock()
+	assert.Equal(t, "swagger: '2.0'", r.schemas["s1"])
+	r.mu.Unlock()
+
+	r.schemaErr = errors.New("schema rejected")
+	assert.NoError(t, RegisterMicroservice())
+	err := waitSchemaStatus(t, SchemaFailed)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "s1")
+}
+
+// fakeSchemaDeleter is a registrator able to delete schemas
+type fakeSchemaDeleter struct {
+	*fakeRegistrator
+	deleted []string
+}
+
+func (f *fakeSchemaDeleter) DeleteSchema(sid, schemaID string) error {
+	f.deleted = append(f.deleted, schemaID)
+	return nil
+}
+
+func TestDeleteStaleSchemas(t *testing.T) {
+	r, d := initBootstrapEnv()
+	registeredSchemas, registeredSchemasSID = nil, ""
+	deleter := &fakeSchemaDeleter{fakeRegistrator: r}
+	runner := NewRegistrationRunner(deleter, d)
+
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, runner.RegisterMicroservice())
+	loadTestSchemas(t, "s1")
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.Empty(t, deleter.deleted, "deletion is opt-in")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.DeleteStaleSchemas = true
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, runner.RegisterMicroservice())
+	loadTestSchemas(t, "s1")
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.Equal(t, []string{"s2"}, deleter.deleted)
+
+	// stale schemas are kept while uploads fail, and deleted once all are uploaded
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, runner.RegisterMicroservice())
+	r.schemaErr = errors.New("schema rejected")
+	loadTestSchemas(t, "s1", "s3")
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.Equal(t, []string{"s2"}, deleter.deleted)
+	assert.Equal(t, []string{"s1", "s2"}, registeredSchemas, "failed schemas are not recorded")
+	r.schemaErr = nil
+	assert.NoError(t, runner.RegisterMicroservice())
+	assert.Equal(t, []string{"s2", "s2"}, deleter.deleted)
+
+	// registrator without DeleteSchema keeps stale schemas
+	loadTestSchemas(t, "s1", "s2")
+	assert.NoError(t, RegisterMicroservice())
+	loadTestSchemas(t, "s1")
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []string{"s2", "s2"}, deleter.deleted)
+	loadTestSchemas(t)
+}
+
+func TestMaxSchemas(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	registrator := &config.GlobalDefinition.Cse.Service.Registry.Registrator
+	loadTestSchemas(t, "s1", "s2", "s3")
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 3, len(r.services[0].Schemas), "unlimited by default")
+
+	registrator.MaxSchemas = 3
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 3, len(r.services[1].Schemas))
+
+	registrator.MaxSchemas = 2
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "found 3 schemas")
+	assert.Equal(t, 2, len(r.services))
+
+	registrator.MaxSchemasPolicy = MaxSchemasTruncate
+	r.schemas = make(map[string]string)
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, []string{"s1", "s2"}, r.services[2].Schemas)
+	assert.NoError(t, waitSchemaStatus(t, SchemaReady))
+	assert.NotContains(t, r.schemas, "s3")
+}
+
+func TestEmptySchemas(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	registrator := &config.GlobalDefinition.Cse.Service.Registry.Registrator
+	loadTestSchemas(t, "s1", "s2", "s3")
+	schema.DefaultSchemaIDsMap["s2"] = ""
+	schema.DefaultSchemaIDsMap["s3"] = " \n"
+	registrator.ValidateSchemas = ValidateSchemasFail
+	assert.NoError(t, RegisterMicroservice(), "skipped by default")
+	assert.Equal(t, []string{"s1", "s2", "s3"}, r.services[0].Schemas, "schema ids are kept")
+	assert.Contains(t, r.schemas, "s1")
+	assert.NotContains(t, r.schemas, "s2")
+	assert.NotContains(t, r.schemas, "s3")
+
+	registrator.EmptySchemas = EmptySchemasReject
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "content of schemas [s2 s3] is empty")
+	assert.Equal(t, 1, len(r.services))
+
+	registrator.EmptySchemas = "drop"
+	assert.Error(t, RegisterMicroservice())
+}
+
+// concurrentSchemaRegistrator fails the schemas in failing and records the max number of concurrent uploads
+type concurrentSchemaRegistrator struct {
+	*fakeRegistrator
+	failing  map[string]bool
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (f *concurrentSchemaRegistrator) AddSchemas(sid, schemaName, schemaInfo string) error {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.max {
+		f.max = f.inFlight
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if f.failing[schemaName] {
+		return errors.New("rejected")
+	}
+	return f.fakeRegistrator.AddSchemas(sid, schemaName, schemaInfo)
+}
+
+func TestSchemaConcurrency(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	ids := []string{"s1", "s2", "s3", "s4", "s5", "s6"}
+	loadTestSchemas(t, ids...)
+	c := &concurrentSchemaRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = c
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 1, c.max, "uploaded one by one by default")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.SchemaConcurrency = 3
+	r.schemas = make(map[string]string)
+	c.max = 0
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 3, c.max)
+	for _, id := range ids {
+		assert.Contains(t, r.schemas, id)
+	}
+	state, _ := SchemaStatus()
+	assert.Equal(t, SchemaReady, state)
+
+	c.failing = map[string]bool{"s5": true, "s2": true}
+	assert.NoError(t, RegisterMicroservice())
+	state, err := SchemaStatus()
+	assert.Equal(t, SchemaFailed, state)
+	assert.EqualError(t, err, "add schemas failed: s2: rejected; s5: rejected", "errors are reported in order of schema ids")
+}
+
+func TestSchemaCompression(t *testing.T) {
+	r, d := initBootstrapEnv()
+	loadTestSchemas(t, "small", "large")
+	large := "swagger: '2.0'\ninfo:\n  description: " + strings.Repeat("hello world ", 100)
+	schema.DefaultSchemaIDsMap["large"] = large
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.SchemaCompressThreshold = 512
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, "swagger: '2.0'", r.schemas["small"], "below threshold is not compressed")
+	assert.True(t, strings.HasPrefix(r.schemas["large"], CompressedSchemaPrefix))
+	assert.True(t, len(r.schemas["large"]) < len(large))
+	content, err := DecompressSchema(r.schemas["large"])
+	assert.NoError(t, err)
+	assert.Equal(t, large, content)
+	content, err = DecompressSchema(r.schemas["small"])
+	assert.NoError(t, err)
+	assert.Equal(t, "swagger: '2.0'", content)
+	_, err = DecompressSchema(CompressedSchemaPrefix + "not gzip")
+	assert.Error(t, err)
+
+	b := &batchRegistrator{fakeRegistrator: r}
+	assert.NoError(t, NewRegistrationRunner(b, d).RegisterMicroservice())
+	assert.True(t, strings.HasPrefix(b.batches[0]["large"], CompressedSchemaPrefix), "compressed in batch too")
+	assert.Equal(t, "swagger: '2.0'", b.batches[0]["small"])
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.SchemaCompressThreshold = 0
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, large, r.schemas["large"], "not compressed by default")
+}
+
+func TestValidateSchemas(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	registrator := &config.GlobalDefinition.Cse.Service.Registry.Registrator
+	loadTestSchemas(t, "s1", "s2", "s3")
+	schema.DefaultSchemaIDsMap["s2"] = "swagger: ['2.0'"
+	schema.DefaultSchemaIDsMap["s3"] = `{"info": {"title": "hello"}}`
+	assert.NoError(t, RegisterMicroservice(), "not validated by default")
+
+	registrator.ValidateSchemas = ValidateSchemasWarn
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 2, len(r.services))
+	assert.Equal(t, "swagger: ['2.0'", r.schemas["s2"], "uploaded as it is")
+
+	registrator.ValidateSchemas = ValidateSchemasFail
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "s2: can not parse content")
+	assert.Contains(t, err.Error(), "s3: content declares neither swagger nor openapi version")
+	assert.Equal(t, 2, len(r.services))
+
+	schema.DefaultSchemaIDsMap["s2"] = "swagger: '2.0'"
+	schema.DefaultSchemaIDsMap["s3"] = `{"openapi": "3.0.0", "info": {"title": "hello"}}`
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 3, len(r.services))
+
+	registrator.ValidateSchemas = "strict"
+	assert.Error(t, RegisterMicroservice())
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// policies of checking advertised protocols against running servers
+const (
+	// ServerCheckWarn logs a warning for protocols advertised without running server, it is the default policy
+	ServerCheckWarn = "warn"
+	// ServerCheckStrict fails the instance registration
+	ServerCheckStrict = "strict"
+	// ServerCheckOff disables the check
+	ServerCheckOff = "off"
+)
+
+// runningServers records protocol servers started by server manager
+var runningServers = make(map[string]bool)
+var runningServersMu sync.RWMutex
+
+// SetServerRunning records whether the protocol server of name is running
+func SetServerRunning(name string, running bool) {
+	runningServersMu.Lock()
+	defer runningServersMu.Unlock()
+	if running {
+		runningServers[name] = true
+		return
+	}
+	delete(runningServers, name)
+}
+
+// checkServers makes sure each advertised protocol in eps has a running server,
+// the ssl endpoint of a protocol is served by the server of that protocol
+func checkServers(eps map[string]string) error {
+	policy := config.GetRegistratorServerCheck()
+	if policy == ServerCheckOff {
+		return nil
+	}
+	runningServersMu.RLock()
+	var missing []string
+	for name := range eps {
+		if !runningServers[strings.TrimSuffix(name, sslEndpointSuffix)] {
+			missing = append(missing, name)
+		}
+	}
+	runningServersMu.RUnlock()
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	switch policy {
+	case ServerCheckStrict:
+		return fmt.Errorf("protocols %v are advertised without running server", missing)
+	case "", ServerCheckWarn:
+		lager.Logger.Warnf("protocols %v are advertised without running server", missing)
+		return nil
+	default:
+		return fmt.Errorf("unknown server check policy [%s]", policy)
+	}
+}
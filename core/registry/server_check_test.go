@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckServers(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	defer func() { runningServers = make(map[string]bool) }()
+	runningServers = make(map[string]bool)
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080", SSLAdvertise: "127.0.0.1:8443"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090"},
+	}
+
+	// warn by default
+	assert.NoError(t, RegisterMicroserviceInstances())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ServerCheck = ServerCheckStrict
+	err := RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), common.ProtocolHighway)
+	assert.Equal(t, 1, len(r.instances))
+
+	SetServerRunning(common.ProtocolRest, true)
+	err = RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), common.ProtocolRest+sslEndpointSuffix)
+
+	SetServerRunning(common.ProtocolHighway, true)
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+
+	SetServerRunning(common.ProtocolHighway, false)
+	assert.Error(t, RegisterMicroserviceInstances())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ServerCheck = ServerCheckOff
+	assert.NoError(t, RegisterMicroserviceInstances())
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.ServerCheck = "unknown"
+	assert.Error(t, RegisterMicroserviceInstances())
+}
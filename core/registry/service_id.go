@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// ServiceIDChangedHandler is notified when registry returns a new id for self micro-service,
+// dependents keyed by the old id should refresh
+type ServiceIDChangedHandler func(oldID, newID string)
+
+// selfIDsMu guards the ids of self micro-service and instance in runtime,
+// registration writes them while background tasks like instance heartbeat read them
+var selfIDsMu sync.RWMutex
+
+// serviceIDSkipped and instanceIDSkipped record whether self ids are synthetic ones set when registration is skipped
+var serviceIDSkipped, instanceIDSkipped bool
+
+// selfIDs returns the ids of self micro-service and instance, empty if not registered
+func selfIDs() (string, string) {
+	selfIDsMu.RLock()
+	defer selfIDsMu.RUnlock()
+	return runtime.ServiceID, runtime.InstanceID
+}
+
+// setSelfServiceID records the id of self micro-service and returns the previous one
+func setSelfServiceID(sid string) string {
+	selfIDsMu.Lock()
+	defer selfIDsMu.Unlock()
+	oldID := runtime.ServiceID
+	runtime.ServiceID = sid
+	serviceIDSkipped = false
+	return oldID
+}
+
+// setSelfInstanceID records the id of self instance, empty means self instance is not registered
+func setSelfInstanceID(iid string) {
+	selfIDsMu.Lock()
+	runtime.InstanceID = iid
+	instanceIDSkipped = false
+	selfIDsMu.Unlock()
+}
+
+var serviceIDChangedHandlers []ServiceIDChangedHandler
+var serviceIDChangedMu sync.RWMutex
+
+// OnServiceIDChanged registers h to be notified when the id of self micro-service changes
+func OnServiceIDChanged(h ServiceIDChangedHandler) {
+	serviceIDChangedMu.Lock()
+	serviceIDChangedHandlers = append(serviceIDChangedHandlers, h)
+	serviceIDChangedMu.Unlock()
+}
+
+// serviceIDChanged invalidates what is keyed by the old id of self micro-service and notifies handlers,
+// self instance registered under the old id is dropped as well, it must be registered again
+func serviceIDChanged(oldID, newID string) {
+	lager.Logger.Warnf("Service id of self micro-service changed from [%s] to [%s]", oldID, newID)
+	for _, iid := range selfInstanceIDs(oldID) {
+		removeAdvertisedEndpoints(iid)
+	}
+	SelfInstancesCache.Delete(oldID)
+	HBService.removeServiceTasks(oldID)
+	setSelfInstanceID("")
+
+	serviceIDChangedMu.RLock()
+	handlers := make([]ServiceIDChangedHandler, len(serviceIDChangedHandlers))
+	copy(handlers, serviceIDChangedHandlers)
+	serviceIDChangedMu.RUnlock()
+	for _, h := range handlers {
+		h(oldID, newID)
+	}
+}
+
+// DataCenterDiscovery is implemented by service discoveries of multi data center registries,
+// in which the same micro-service has an id per data center
+type DataCenterDiscovery interface {
+	GetMicroServiceIDInDataCenter(dataCenter, appID, microServiceName, version, env string) (string, error)
+}
+
+// lookupMicroServiceID returns the id of micro-service app:name:version:env,
+// if dataCenterScope is enabled and data center of self instance is named, the id in that data center is returned,
+// discovery must implement DataCenterDiscovery for it, otherwise the lookup is not scoped
+func (r *RegistrationRunner) lookupMicroServiceID(app, name, version, env string) (string, error) {
+	if !config.GetServiceDiscoveryDataCenterScope() || config.GlobalDefinition.DataCenter == nil || config.GlobalDefinition.DataCenter.Name == "" {
+		return r.Discovery.GetMicroServiceID(app, name, version, env)
+	}
+	dd, ok := r.Discovery.(DataCenterDiscovery)
+	if !ok {
+		lager.Logger.Warnf("Service discovery does not support data center scope, look up %s:%s:%s:%s in all data centers",
+			app, name, version, env)
+		return r.Discovery.GetMicroServiceID(app, name, version, env)
+	}
+	return dd.GetMicroServiceIDInDataCenter(config.GlobalDefinition.DataCenter.Name, app, name, version, env)
+}
+
+// lookupOrRegisterMicroServiceID returns the id of micro-service app:name:version:env,
+// if it is not found and registerOnNotFound is enabled, self micro-service is registered and looked up once more
+func (r *RegistrationRunner) lookupOrRegisterMicroServiceID(app, name, version, env string) (string, error) {
+	sid, err := r.lookupMicroServiceID(app, name, version, env)
+	if err != nil || sid != "" || !config.GetRegistratorRegisterOnNotFound() {
+		return sid, err
+	}
+	lager.Logger.Warnf("Micro service %s:%s:%s:%s is not found, register it", app, name, version, env)
+	// registered only once, so that a registry never exposing the service does not loop forever
+	if err := r.RegisterMicroservice(); err != nil {
+		return "", err
+	}
+	if sid, err = r.lookupMicroServiceID(app, name, version, env); err == nil && sid == "" {
+		return "", fmt.Errorf("micro service %s:%s:%s:%s is not found after registration", app, name, version, env)
+	}
+	return sid, err
+}
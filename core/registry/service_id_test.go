@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceIDChanged(t *testing.T) {
+	r, d := initBootstrapEnv()
+	var events [][2]string
+	OnServiceIDChanged(func(oldID, newID string) {
+		events = append(events, [2]string{oldID, newID})
+	})
+	defer func() { serviceIDChangedHandlers = nil }()
+
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	HBService.AddTask("sid", "iid")
+	_, ok := SelfInstancesCache.Get("sid")
+	assert.True(t, ok)
+
+	// registered again with the same id
+	assert.NoError(t, RegisterMicroservice())
+	assert.Empty(t, events)
+	assert.Equal(t, "iid", runtime.InstanceID)
+
+	// registry was reset and assigned a new id
+	r.sid, d.sid = "newSid", "newSid"
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, [][2]string{{"sid", "newSid"}}, events)
+	assert.Equal(t, "newSid", runtime.ServiceID)
+	assert.Equal(t, "", runtime.InstanceID)
+	_, ok = SelfInstancesCache.Get("sid")
+	assert.False(t, ok)
+	HBService.mux.Lock()
+	assert.NotContains(t, HBService.instances, "sid/iid")
+	HBService.mux.Unlock()
+}
+
+// dataCenterDiscovery has an id of self micro-service per data center
+type dataCenterDiscovery struct {
+	*fakeDiscovery
+	ids map[string]string
+}
+
+func (d *dataCenterDiscovery) GetMicroServiceIDInDataCenter(dc, appID, microServiceName, version, env string) (string, error) {
+	return d.ids[dc], nil
+}
+
+func TestLookupMicroServiceIDInDataCenter(t *testing.T) {
+	r, d := initBootstrapEnv()
+	dd := &dataCenterDiscovery{fakeDiscovery: d, ids: map[string]string{"dc-1": "sid-1", "dc-2": "sid-2"}}
+	DefaultServiceDiscoveryService = dd
+	config.GlobalDefinition.DataCenter = &model.DataCenterInfo{Name: "dc-2", AvailableZone: "az-1"}
+	runtime.ServiceID = "sid"
+
+	// not scoped by default
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, []string{r.iid}, selfInstanceIDs("sid"))
+
+	config.GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.DataCenterScope = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, []string{r.iid}, selfInstanceIDs("sid-2"))
+	assert.Empty(t, selfInstanceIDs("sid-1"))
+
+	// discovery without data center support is not scoped
+	SelfInstancesCache.Flush()
+	DefaultServiceDiscoveryService = d
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, []string{r.iid}, selfInstanceIDs("sid"))
+	assert.Empty(t, selfInstanceIDs("sid-2"))
+}
+
+// lazyDiscovery finds self micro-service only after it is registered, unless hidden
+type lazyDiscovery struct {
+	*fakeDiscovery
+	r       *fakeRegistrator
+	hidden  bool
+	lookups int
+}
+
+func (d *lazyDiscovery) GetMicroServiceID(appID, microServiceName, version, env string) (string, error) {
+	d.lookups++
+	if d.hidden || len(d.r.services) == 0 {
+		return "", nil
+	}
+	return d.r.sid, nil
+}
+
+func TestRegisterOnNotFound(t *testing.T) {
+	r, fd := initBootstrapEnv()
+	d := &lazyDiscovery{fakeDiscovery: fd, r: r}
+	DefaultServiceDiscoveryService = d
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RegisterOnNotFound = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, 2, d.lookups)
+	assert.Equal(t, "sid", runtime.ServiceID)
+	assert.Equal(t, "iid", runtime.InstanceID)
+
+	// found, not registered again
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, 3, d.lookups)
+
+	// registered once only if registry never exposes it
+	r.services = nil
+	d.hidden, d.lookups = true, 0
+	err := RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found after registration")
+	assert.Equal(t, 1, len(r.services))
+	assert.Equal(t, 2, d.lookups)
+	assert.Equal(t, 2, len(r.instances))
+}
@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"github.com/go-chassis/go-chassis/core/config"
+)
+
+// serviceKey is the composite key registry identifies a micro-service by
+type serviceKey struct {
+	App     string
+	Name    string
+	Version string
+	Env     string
+}
+
+// String returns the key as app:name:version:env
+func (k serviceKey) String() string {
+	return k.App + ":" + k.Name + ":" + k.Version + ":" + k.Env
+}
+
+// registrationKey returns the key self micro-service is registered and looked up with,
+// components of service_description.serviceKey override the ones computed from service description and app
+func registrationKey() serviceKey {
+	desc := config.MicroserviceDefinition.ServiceDescription
+	o := desc.ServiceKey
+	return serviceKey{
+		App:     firstNonEmpty(o.App, registrationApp()),
+		Name:    firstNonEmpty(o.Name, desc.Name),
+		Version: firstNonEmpty(o.Version, desc.Version),
+		Env:     firstNonEmpty(o.Environment, desc.Environment),
+	}
+}
@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// keyDiscovery records the key micro-service id is looked up with
+type keyDiscovery struct {
+	*fakeDiscovery
+	key serviceKey
+}
+
+func (d *keyDiscovery) GetMicroServiceID(appID, microServiceName, version, env string) (string, error) {
+	d.key = serviceKey{App: appID, Name: microServiceName, Version: version, Env: env}
+	return d.fakeDiscovery.GetMicroServiceID(appID, microServiceName, version, env)
+}
+
+func TestServiceKeyOverride(t *testing.T) {
+	r, d := initBootstrapEnv()
+	kd := &keyDiscovery{fakeDiscovery: d}
+	DefaultServiceDiscoveryService = kd
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Environment = "development"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	implicit := serviceKey{App: "default", Name: "TestService", Version: "0.0.1", Env: "development"}
+	assert.Equal(t, implicit, kd.key)
+
+	desc.ServiceKey.App = "legacy"
+	desc.ServiceKey.Name = "test-service"
+	desc.ServiceKey.Environment = "production"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	ms := r.services[1]
+	registered := serviceKey{App: ms.AppID, Name: ms.ServiceName, Version: ms.Version, Env: ms.Environment}
+	overridden := serviceKey{App: "legacy", Name: "test-service", Version: "0.0.1", Env: "production"}
+	assert.Equal(t, overridden, registered, "version is not overridden")
+	assert.Equal(t, overridden, kd.key, "lookup uses the same key")
+
+	desc.ServiceKey.Version = "v1"
+	desc.ServiceKey.Name = "bad name"
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "service_description.serviceKey.version")
+	assert.Contains(t, err.Error(), "service_description.serviceKey.name")
+	assert.Equal(t, 2, len(r.services))
+}
@@ -26,6 +26,8 @@ func TestCacheManager_AutoSync(t *testing.T) {
 	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
 
 	config.Init()
+	// bound registration retries in case service center is unreachable
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "1s"
 	registry.Enable()
 	registry.DoRegister()
 	t.Log("持有id", runtime.ServiceID)
@@ -144,6 +146,8 @@ func TestCacheManager_MakeSchemaIndex(t *testing.T) {
 	archaius.AddKeyValue("cse.service.registry.autoSchemaIndex", true)
 	config.GlobalDefinition.Cse.Service.Registry.ServiceDiscovery.RefreshInterval = "1"
 	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	// bound registration retries in case service center is unreachable
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "1s"
 	registry.Enable()
 	registry.DoRegister()
 	time.Sleep(time.Second * 1)
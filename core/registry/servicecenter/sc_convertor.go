@@ -13,7 +13,7 @@ func ToMicroService(scs *client.MicroService) *registry.MicroService {
 	cs.ServiceName = scs.ServiceName
 	cs.Version = scs.Version
 	cs.AppID = scs.AppID
-	cs.Metadata = scs.Properties
+	cs.Metadata = decodeMetadata(registry.ChassisMetadata(scs.Properties))
 	cs.Schemas = scs.Schemas
 	cs.Level = scs.Level
 	cs.Status = scs.Status
@@ -34,7 +34,7 @@ func ToSCService(cs *registry.MicroService) *client.MicroService {
 	scs.Version = cs.Version
 	scs.AppID = cs.AppID
 	scs.Environment = cs.Environment
-	scs.Properties = cs.Metadata
+	scs.Properties = registry.RegistryMetadata(cs.Metadata)
 	scs.Schemas = cs.Schemas
 	scs.Level = cs.Level
 	scs.Status = cs.Status
@@ -61,11 +61,12 @@ func ToSCService(cs *registry.MicroService) *client.MicroService {
 func ToMicroServiceInstance(ins *client.MicroServiceInstance) *registry.MicroServiceInstance {
 	msi := &registry.MicroServiceInstance{
 		InstanceID: ins.InstanceID,
-		Metadata:   ins.Properties,
+		Metadata:   decodeMetadata(ins.Properties),
 		Status:     ins.Status,
 	}
 	m, p := registry.GetProtocolMap(ins.Endpoints)
 	msi.EndpointsMap = m
+	msi.EndpointMarks = registry.GetProtocolMarks(ins.Endpoints)
 	msi.DefaultEndpoint = m[p]
 	msi.DefaultProtocol = p
 	if ins.DataCenterInfo != nil {
@@ -85,7 +86,11 @@ func ToMicroServiceInstance(ins *client.MicroServiceInstance) *registry.MicroSer
 // ToSCInstance assign registry micro-service instance parameters to model micro-service instance parameters
 func ToSCInstance(msi *registry.MicroServiceInstance) *client.MicroServiceInstance {
 	si := &client.MicroServiceInstance{}
-	eps := registry.GetProtocolList(msi.EndpointsMap)
+	m := make(map[string]string, len(msi.EndpointsMap))
+	for p := range msi.EndpointsMap {
+		m[p] = msi.Endpoint(p)
+	}
+	eps := registry.GetProtocolList(m)
 	si.InstanceID = msi.InstanceID
 	si.Endpoints = eps
 	si.Properties = msi.Metadata
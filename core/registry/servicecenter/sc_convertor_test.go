@@ -0,0 +1,77 @@
+package servicecenter_test
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/core/registry"
+	"github.com/go-chassis/go-chassis/core/registry/servicecenter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceEndpointsRoundTrip(t *testing.T) {
+	config.GlobalDefinition = &model.GlobalCfg{}
+	eps := map[string]string{
+		"rest":     "10.0.0.1:8080?sslEnabled=false",
+		"rest-ssl": "10.0.0.1:8443?sslEnabled=true",
+	}
+	msi := servicecenter.ToMicroServiceInstance(servicecenter.ToSCInstance(&registry.MicroServiceInstance{EndpointsMap: eps}))
+	assert.Equal(t, map[string]string{"rest": "10.0.0.1:8080", "rest-ssl": "10.0.0.1:8443"}, msi.EndpointsMap, "addresses are bare")
+	assert.Equal(t, msi.EndpointsMap[msi.DefaultProtocol], msi.DefaultEndpoint)
+	assert.Equal(t, eps["rest-ssl"], msi.Endpoint("rest-ssl"), "marks are kept through service center")
+
+	again := servicecenter.ToMicroServiceInstance(servicecenter.ToSCInstance(msi))
+	assert.Equal(t, msi.EndpointsMap, again.EndpointsMap)
+	assert.Equal(t, msi.EndpointMarks, again.EndpointMarks)
+}
+
+func TestInstanceEndpointWeightRoundTrip(t *testing.T) {
+	eps := map[string]string{"rest": "10.0.0.1:8080?weight=3", "highway": "10.0.0.1:9090"}
+	msi := servicecenter.ToMicroServiceInstance(servicecenter.ToSCInstance(&registry.MicroServiceInstance{EndpointsMap: eps}))
+	w, err := registry.EndpointWeight(msi.Endpoint("rest"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, w)
+	w, err = registry.EndpointWeight(msi.Endpoint("highway"))
+	assert.NoError(t, err)
+	assert.Equal(t, registry.DefaultEndpointWeight, w)
+}
+
+func TestInstanceEndpointSchemeRoundTrip(t *testing.T) {
+	eps := map[string]string{"rest": "10.0.0.1:8443?scheme=https&sslEnabled=true"}
+	msi := servicecenter.ToMicroServiceInstance(servicecenter.ToSCInstance(&registry.MicroServiceInstance{EndpointsMap: eps}))
+	assert.Equal(t, "https", registry.EndpointScheme(msi.Endpoint("rest")))
+	assert.Equal(t, "10.0.0.1:8443", msi.EndpointsMap["rest"])
+	assert.Equal(t, "rest", msi.DefaultProtocol)
+}
+
+func TestMetadataEncodingRoundTrip(t *testing.T) {
+	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = registry.MetadataEncodingBase64
+	defer func() { config.GlobalDefinition.Cse.Service.Registry.Registrator.MetadataEncoding = "" }()
+	md := map[string]string{"note": "a b", registry.MDAllowCrossApp: "true"}
+	encoded := map[string]string{"note": "YSBi", registry.MDAllowCrossApp: "true"}
+
+	msi := servicecenter.ToMicroServiceInstance(servicecenter.ToSCInstance(&registry.MicroServiceInstance{Metadata: encoded}))
+	assert.Equal(t, "a b", msi.Metadata["note"])
+	assert.Equal(t, "true", msi.Metadata[registry.MDAllowCrossApp])
+
+	ms := servicecenter.ToMicroService(servicecenter.ToSCService(&registry.MicroService{Metadata: encoded}))
+	assert.Equal(t, md, ms.Metadata)
+
+	plain := map[string]string{"note": "a b"}
+	ms = servicecenter.ToMicroService(servicecenter.ToSCService(&registry.MicroService{Metadata: plain}))
+	assert.Equal(t, plain, ms.Metadata, "kept as it is if not encoded")
+}
+
+func TestRegistryKeysTranslated(t *testing.T) {
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.KeyPrefix = "cse."
+	md := map[string]string{"cse.allowCrossApp": "true", "cse.nodeIP": "10.0.0.1"}
+
+	scs := servicecenter.ToSCService(&registry.MicroService{Metadata: md})
+	assert.Equal(t, map[string]string{registry.MDAllowCrossApp: "true", "cse.nodeIP": "10.0.0.1"}, scs.Properties)
+	assert.Equal(t, md, servicecenter.ToMicroService(scs).Metadata)
+}
@@ -574,6 +574,10 @@ func (r *ContractDiscovery) GetSchema(microServiceID, schemaName string) ([]byte
 		openlogging.GetLogger().Errorf("GetSchema failed: %s", err)
 		return []byte(""), err
 	}
+	if schemaContent, err = decompressSchemaContent(schemaContent); err != nil {
+		openlogging.GetLogger().Errorf("GetSchema failed: %s", err)
+		return []byte(""), err
+	}
 	openlogging.GetLogger().Debugf("GetSchema success.")
 	return schemaContent, nil
 
@@ -24,6 +24,8 @@ func TestServicecenter_RegisterServiceAndInstance(t *testing.T) {
 	runtime.Init()
 	t.Log(os.Getenv("CHASSIS_HOME"))
 	lager.Initialize("", "INFO", "", "size", true, 1, 10, 7)
+	// bound registration retries in case service center is unreachable
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.RetryBudget = "1s"
 	registry.Enable()
 	registry.DoRegister()
 
@@ -1,6 +1,9 @@
 package servicecenter
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/go-chassis/go-chassis/core/common"
 	"github.com/go-chassis/go-chassis/core/lager"
 	"github.com/go-chassis/go-chassis/core/registry"
@@ -21,14 +24,36 @@ func parseSchemaContent(content []byte) (registry.SchemaContent, error) {
 	if err = yaml.Unmarshal(content, schema); err != nil {
 		return *schemaContent, err
 	}
+	// schema may be uploaded compressed
+	raw, err := registry.DecompressSchema(schema.Schema)
+	if err != nil {
+		return *schemaContent, err
+	}
 
-	if err = yaml.Unmarshal([]byte(schema.Schema), schemaContent); err != nil {
+	if err = yaml.Unmarshal([]byte(raw), schemaContent); err != nil {
 		return *schemaContent, err
 	}
 
 	return *schemaContent, nil
 }
 
+// decompressSchemaContent returns schema content read back from service center with its schema decompressed
+func decompressSchemaContent(content []byte) ([]byte, error) {
+	schema := &registry.Schema{}
+	if err := yaml.Unmarshal(content, schema); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(schema.Schema, registry.CompressedSchemaPrefix) {
+		return content, nil
+	}
+	raw, err := registry.DecompressSchema(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = raw
+	return json.Marshal(schema)
+}
+
 // parseSchemaContent parse schema content into SchemaContent structure
 func unmarshalSchemaContent(content []byte) (*registry.SchemaContent, error) {
 	var (
@@ -40,14 +65,30 @@ func unmarshalSchemaContent(content []byte) (*registry.SchemaContent, error) {
 	if err = yaml.Unmarshal(content, schema); err != nil {
 		return schemaContent, err
 	}
+	// schema may be uploaded compressed
+	raw, err := registry.DecompressSchema(schema.Schema)
+	if err != nil {
+		return schemaContent, err
+	}
 
-	if err = yaml.Unmarshal([]byte(schema.Schema), schemaContent); err != nil {
+	if err = yaml.Unmarshal([]byte(raw), schemaContent); err != nil {
 		return schemaContent, err
 	}
 
 	return schemaContent, nil
 }
 
+// decodeMetadata returns metadata read back from service center with values decoded by registrator.metadataEncoding,
+// metadata which can not be decoded is kept as it is, like the one registered by other frameworks
+func decodeMetadata(md map[string]string) map[string]string {
+	decoded, err := registry.DecodeMetadata(md)
+	if err != nil {
+		lager.Logger.Warnf("Decode metadata failed, keep it as it is: %s", err)
+		return md
+	}
+	return decoded
+}
+
 // filterInstances filter instances
 func filterInstances(providerInstances []*client.MicroServiceInstance) []*registry.MicroServiceInstance {
 	instances := make([]*registry.MicroServiceInstance, 0)
@@ -0,0 +1,54 @@
+package servicecenter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCompressedSchemaContent(t *testing.T) {
+	raw := "swagger: '2.0'\nbasePath: /hello\n"
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(raw))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	for _, s := range []string{raw, registry.CompressedSchemaPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())} {
+		content, err := json.Marshal(&registry.Schema{Schema: s})
+		assert.NoError(t, err)
+		parsed, err := parseSchemaContent(content)
+		assert.NoError(t, err)
+		assert.Equal(t, "/hello", parsed.BasePath)
+		unmarshalled, err := unmarshalSchemaContent(content)
+		assert.NoError(t, err)
+		assert.Equal(t, "2.0", unmarshalled.Swagger)
+	}
+
+	content, _ := json.Marshal(&registry.Schema{Schema: registry.CompressedSchemaPrefix + "not base64"})
+	_, err = parseSchemaContent(content)
+	assert.Error(t, err)
+	_, err = decompressSchemaContent(content)
+	assert.Error(t, err)
+}
+
+func TestDecompressSchemaContent(t *testing.T) {
+	plain, _ := json.Marshal(&registry.Schema{Schema: "swagger: '2.0'"})
+	content, err := decompressSchemaContent(plain)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, content, "uncompressed content is returned as it is")
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write([]byte("swagger: '2.0'"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	compressed, _ := json.Marshal(&registry.Schema{Schema: registry.CompressedSchemaPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())})
+	content, err = decompressSchemaContent(compressed)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(plain), string(content))
+}
@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// prefix of synthetic ids set when registration is skipped
+const syntheticIDPrefix = "local-"
+
+// skipRegistration sets synthetic ids of phase for downstream code instead of registering,
+// ids are made of the service key and host name so that they stay the same across runs
+func skipRegistration(phase string) {
+	switch phase {
+	case PhaseService:
+		key := registrationKey()
+		sid := syntheticIDPrefix + key.App + ":" + key.Name + ":" + key.Version
+		selfIDsMu.Lock()
+		runtime.ServiceID = sid
+		serviceIDSkipped = true
+		selfIDsMu.Unlock()
+		lager.Logger.Warnf("Registration is disabled, use synthetic service id [%s]", sid)
+	case PhaseInstance:
+		iid := syntheticIDPrefix + runtime.HostName
+		selfIDsMu.Lock()
+		runtime.InstanceID = iid
+		instanceIDSkipped = true
+		selfIDsMu.Unlock()
+		runtime.InstanceStatus = runtime.StatusRunning
+		lager.Logger.Warnf("Registration is disabled, use synthetic instance id [%s]", iid)
+	}
+}
+
+// registrationSkipped returns whether registration is disabled or self ids are synthetic,
+// registry is never called for self micro-service and instance then, changes are only kept locally
+func registrationSkipped() bool {
+	if config.GetRegistratorSkip() {
+		return true
+	}
+	selfIDsMu.RLock()
+	defer selfIDsMu.RUnlock()
+	return serviceIDSkipped || instanceIDSkipped
+}
+
+// RegistrationActive returns whether self instance is registered to registry,
+// it is false when registrator is disabled, thus not enabled, or registration is skipped
+func RegistrationActive() bool {
+	return DefaultRegistrator != nil && !registrationSkipped()
+}
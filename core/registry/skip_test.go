@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipRegistration(t *testing.T) {
+	r, d := initBootstrapEnv()
+	defer initBootstrapEnv()
+	runtime.HostName = "host"
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Skip = true
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "local-default:TestService:0.0.1", runtime.ServiceID)
+	assert.Equal(t, "local-host", runtime.InstanceID)
+	assert.Empty(t, r.services)
+	assert.Empty(t, r.instances)
+	assert.Empty(t, r.schemas)
+	assert.Empty(t, d.appID, "discovery is not queried")
+}
+
+func TestSkipRegistrationByEnv(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	os.Setenv(common.EnvSkipRegistration, "true")
+	defer os.Unsetenv(common.EnvSkipRegistration)
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Empty(t, r.services)
+	assert.Empty(t, r.instances)
+
+	os.Setenv(common.EnvSkipRegistration, "false")
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 1, len(r.services))
+}
+
+func TestSkipRegistrationNoRegistryCalls(t *testing.T) {
+	r, d := initBootstrapEnv()
+	defer initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Skip = true
+	config.GlobalDefinition.Cse.Service.Registry.Scope = common.ScopeFull
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.VerifyScope = true
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	assert.NoError(t, verifyScope())
+	assert.NoError(t, DefaultInstanceHeartbeat.Start())
+	state, _ := DefaultInstanceHeartbeat.Status()
+	assert.Equal(t, HeartbeatStopped, state)
+	DefaultInstanceHeartbeat.beat()
+	assert.NoError(t, DefaultMetadataFileWatcher.Start())
+	assert.NoError(t, UpdateInstanceMetadata(map[string]string{"zone": "z2"}))
+	assert.Equal(t, "z2", GetSelfMetadata()["zone"], "kept locally")
+	assert.NoError(t, UpdateInstance("", map[string]string{"zone": "z3"}))
+	assert.NoError(t, DrainInstance(time.Millisecond))
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+	assert.NoError(t, UpdateAdvertisedEndpoints(map[string]string{"rest": "10.0.0.9:8080"}))
+	assert.NoError(t, ForceExpireSelfInstance())
+
+	assert.Empty(t, r.services)
+	assert.Empty(t, r.instances)
+	assert.Empty(t, r.status)
+	assert.Empty(t, r.heartbeats)
+	assert.Empty(t, r.unregistered)
+	assert.Zero(t, r.propertyUpdates)
+	assert.Empty(t, d.appID, "discovery is not queried")
+}
+
+func TestRegistrationSkippedExplicit(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	r.sid = "local-sid"
+	r.iid = "local-iid"
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.False(t, registrationSkipped(), "registry ids are not taken as synthetic")
+
+	skipRegistration(PhaseInstance)
+	assert.True(t, registrationSkipped())
+	assert.False(t, RegistrationActive())
+	setSelfInstanceID("iid")
+	assert.False(t, registrationSkipped(), "cleared once registered")
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+)
+
+// statusTransitions is the status state machine of self instance,
+// key is the current status, value is the status it can change to
+var statusTransitions = map[string][]string{
+	"":                         {runtime.StatusRunning, runtime.StatusOutOfService},
+	runtime.StatusRunning:      {runtime.StatusOutOfService, runtime.StatusDown},
+	runtime.StatusOutOfService: {runtime.StatusRunning, runtime.StatusDown},
+	runtime.StatusDown:         {runtime.StatusRunning, runtime.StatusOutOfService},
+}
+
+var statusMu sync.Mutex
+
+// canTransit returns whether self instance can change status from one to another
+func canTransit(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// initialStatus returns the status self instance registers with
+func initialStatus(s string) (string, error) {
+	switch s {
+	case "":
+		return runtime.StatusRunning, nil
+	case runtime.StatusRunning, runtime.StatusOutOfService:
+		return s, nil
+	default:
+		return "", fmt.Errorf("initial status must be %s or %s, got %s",
+			runtime.StatusRunning, runtime.StatusOutOfService, s)
+	}
+}
+
+// updateInstanceStatus changes the status of self instance in registry, only locally if registration is disabled
+func updateInstanceStatus(status string) error {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	sid, iid := selfIDs()
+	if sid == "" || iid == "" {
+		return ErrInstanceNotRegistered
+	}
+	if runtime.InstanceStatus == status {
+		return nil
+	}
+	if !canTransit(runtime.InstanceStatus, status) {
+		return fmt.Errorf("instance status can not change from %s to %s", runtime.InstanceStatus, status)
+	}
+	if registrationSkipped() {
+		lager.Logger.Debugf("Registration is disabled, instance status is only changed locally")
+	} else if err := DefaultRegistrator.UpdateMicroServiceInstanceStatus(sid, iid, status); err != nil {
+		lager.Logger.Errorf("Update instance status to %s failed: %s", status, err)
+		return err
+	}
+	lager.Logger.Infof("Instance status changed from %s to %s", runtime.InstanceStatus, status)
+	runtime.InstanceStatus = status
+	return nil
+}
+
+// EnableInstance puts self instance into rotation by changing its status to UP
+func EnableInstance() error {
+	return updateInstanceStatus(runtime.StatusRunning)
+}
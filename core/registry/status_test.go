@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterOutOfService(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	runtime.InstanceStatus = ""
+	runtime.ServiceID = "sid"
+	config.MicroserviceDefinition.ServiceDescription.Instance.InitialStatus = runtime.StatusOutOfService
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, runtime.StatusOutOfService, r.instances[0].Status)
+	assert.Equal(t, runtime.StatusOutOfService, runtime.InstanceStatus)
+
+	assert.NoError(t, EnableInstance())
+	assert.Equal(t, []string{runtime.StatusRunning}, r.status)
+	assert.Equal(t, runtime.StatusRunning, runtime.InstanceStatus)
+
+	// already enabled
+	assert.NoError(t, EnableInstance())
+	assert.Equal(t, 1, len(r.status))
+}
+
+func TestInitialStatus(t *testing.T) {
+	s, err := initialStatus("")
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.StatusRunning, s)
+	_, err = initialStatus(runtime.StatusDown)
+	assert.Error(t, err)
+
+	assert.True(t, canTransit(runtime.StatusOutOfService, runtime.StatusRunning))
+	assert.False(t, canTransit("", runtime.StatusDown))
+}
@@ -1,6 +1,10 @@
 package registry
 
-import "github.com/go-chassis/go-chassis/core/common"
+import (
+	"strings"
+
+	"github.com/go-chassis/go-chassis/core/common"
+)
 
 // MicroService struct having full info about micro-service
 type MicroService struct {
@@ -40,8 +44,19 @@ type MicroServiceInstance struct {
 	DefaultEndpoint string
 	Status          string
 	EndpointsMap    map[string]string
-	Metadata        map[string]string
-	DataCenterInfo  *DataCenterInfo
+	// EndpointMarks are the marks of endpoints like sslEnabled and weight as raw query, keyed by protocol
+	EndpointMarks  map[string]string
+	Metadata       map[string]string
+	DataCenterInfo *DataCenterInfo
+}
+
+// Endpoint returns the endpoint of protocol along with its marks, like host:port?sslEnabled=true
+func (m *MicroServiceInstance) Endpoint(protocol string) string {
+	ep := m.EndpointsMap[protocol]
+	if marks := m.EndpointMarks[protocol]; marks != "" && !strings.Contains(ep, "?") {
+		ep += "?" + marks
+	}
+	return ep
 }
 
 func (m *MicroServiceInstance) appID() string   { return m.Metadata[common.BuildinTagApp] }
@@ -0,0 +1,33 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// syntheticEndpoints overrides the endpoints of protocols with the synthetic test targets,
+// it tells whether any endpoint is overridden, overrides are refused unless synthetic endpoints are enabled
+func syntheticEndpoints(eps map[string]string, s model.SyntheticEndpointsStruct) (map[string]string, bool, error) {
+	if len(s.Endpoints) == 0 {
+		return eps, false, nil
+	}
+	if !s.Enabled {
+		lager.Logger.Warnf("synthetic endpoints %v are configured but not enabled, they are ignored", s.Endpoints)
+		return eps, false, nil
+	}
+	overridden := copyMetadata(eps)
+	for _, name := range sortedKeys(s.Endpoints) {
+		if _, ok := eps[name]; !ok {
+			return nil, false, fmt.Errorf("synthetic endpoint of protocol [%s] overrides nothing, protocol is not advertised", name)
+		}
+		ep := s.Endpoints[name]
+		if err := validEndpoint(ep); err != nil {
+			return nil, false, fmt.Errorf("synthetic endpoint of protocol [%s] is invalid: %s", name, err)
+		}
+		overridden[name] = ep
+	}
+	lager.Logger.Warnf("instance endpoints %v are overridden by synthetic endpoints for testing", sortedKeys(s.Endpoints))
+	return overridden, true, nil
+}
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyntheticEndpoints(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	synthetic := &config.MicroserviceDefinition.ServiceDescription.Instance.Synthetic
+	synthetic.Endpoints = map[string]string{"rest": "chaos-proxy:18080"}
+	// refused unless enabled
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{"rest": "127.0.0.1:8080"}, r.instances[0].EndpointsMap)
+	_, ok := r.instances[0].Metadata[MDSynthetic]
+	assert.False(t, ok)
+
+	synthetic.Enabled = true
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, map[string]string{"rest": "chaos-proxy:18080"}, r.instances[1].EndpointsMap)
+	assert.Equal(t, "true", r.instances[1].Metadata[MDSynthetic])
+
+	synthetic.Endpoints = map[string]string{"grpc": "chaos-proxy:18081"}
+	assert.Error(t, RegisterMicroserviceInstances())
+	synthetic.Endpoints = map[string]string{"rest": "chaos-proxy"}
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 2, len(r.instances))
+}
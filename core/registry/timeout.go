@@ -0,0 +1,205 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/lager"
+)
+
+// registrator operations which support a timeout
+const (
+	OpRegisterService  = "registerService"
+	OpAddSchemas       = "addSchemas"
+	OpRegisterInstance = "registerInstance"
+)
+
+// operationTimeout returns the timeout of a registrator operation,
+// it falls back to the overall registrator timeout if the operation has no timeout,
+// zero means no timeout
+func operationTimeout(op string) time.Duration {
+	ops := config.GetRegistratorOperations()
+	var t string
+	switch op {
+	case OpRegisterService:
+		t = ops.RegisterService.Timeout
+	case OpAddSchemas:
+		t = ops.AddSchemas.Timeout
+	case OpRegisterInstance:
+		t = ops.RegisterInstance.Timeout
+	}
+	if t == "" {
+		t = config.GetRegistratorTimeout()
+	}
+	if t == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(t)
+	if err != nil {
+		lager.Logger.Warnf("timeout of %s is invalid [%s], no timeout is used: %s", op, t, err)
+		return 0
+	}
+	return d
+}
+
+// ContextRegistrator is implemented by registrators whose registration calls honor ctx,
+// ctx carries the timeout of the operation and the idempotency key of the registration
+type ContextRegistrator interface {
+	RegisterServiceWithContext(ctx context.Context, microService *MicroService) (string, error)
+	RegisterServiceInstanceWithContext(ctx context.Context, microServiceID string, instance *MicroServiceInstance) (string, error)
+	AddSchemasWithContext(ctx context.Context, microServiceID, schemaName, schemaInfo string) error
+}
+
+type idempotencyKeyCtx struct{}
+
+// IdempotencyKey returns the idempotency key carried by ctx of a ContextRegistrator call, empty if there is none
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key
+}
+
+// callWithTimeout runs a registrator operation under the timeout of op,
+// the call is short-circuited if registration circuit breaker is open, and throttled by the registration rate limit
+func callWithTimeout(op string, f func() error) error {
+	_, _, err := callForIDs(op, func() (string, string, error) {
+		return "", "", f()
+	})
+	return err
+}
+
+// callForID runs a registrator operation returning an id like callWithTimeout
+func callForID(op string, f func() (string, error)) (string, error) {
+	id, _, err := callForIDs(op, func() (string, string, error) {
+		id, err := f()
+		return id, "", err
+	})
+	return id, err
+}
+
+// callForIDs runs a registrator operation returning a service id and an instance id like callWithTimeout
+func callForIDs(op string, f func() (string, string, error)) (string, string, error) {
+	return guardedCall(func() callResult {
+		return runWithTimeout(op, f)
+	})
+}
+
+// callContext runs a ContextRegistrator operation with a context carrying key and the timeout of op, like callWithTimeout
+func callContext(op, key string, f func(ctx context.Context) error) error {
+	_, err := callContextForID(op, key, func(ctx context.Context) (string, error) {
+		return "", f(ctx)
+	})
+	return err
+}
+
+// callContextForID runs a ContextRegistrator operation returning an id like callContext
+func callContextForID(op, key string, f func(ctx context.Context) (string, error)) (string, error) {
+	sid, _, err := guardedCall(func() callResult {
+		ctx := context.WithValue(context.Background(), idempotencyKeyCtx{}, key)
+		d := operationTimeout(op)
+		if d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		id, err := f(ctx)
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return callResult{err: timeoutError(op, d)}
+		}
+		return callResult{sid: id, err: err}
+	})
+	return sid, err
+}
+
+// guardedCall runs call unless registration circuit breaker is open, throttled by the registration rate limit
+func guardedCall(call func() callResult) (string, string, error) {
+	var res callResult
+	err := registrationBreaker.call(func() error {
+		registrationLimiter.take()
+		res = call()
+		return res.err
+	})
+	return res.sid, res.iid, err
+}
+
+// callResult carries what a registrator operation returns back from the goroutine running it
+type callResult struct {
+	sid string
+	iid string
+	err error
+}
+
+func timeoutError(op string, d time.Duration) error {
+	return fmt.Errorf("registrator operation %s timeout after %s", op, d)
+}
+
+// runWithTimeout runs f and returns its result, or a timeout error once the timeout of op expires,
+// the timeout is client side only, f keeps running and an instance it registers late is kept for reconcileLateInstances
+func runWithTimeout(op string, f func() (string, string, error)) callResult {
+	d := operationTimeout(op)
+	if d <= 0 {
+		sid, iid, err := f()
+		return callResult{sid: sid, iid: iid, err: err}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	results := make(chan callResult, 1)
+	go func() {
+		sid, iid, err := f()
+		results <- callResult{sid: sid, iid: iid, err: err}
+	}()
+	select {
+	case res := <-results:
+		return res
+	case <-timer.C:
+		go func() {
+			late := <-results
+			if late.err != nil {
+				return
+			}
+			lager.Logger.Warnf("registrator operation %s succeeded after timeout", op)
+			if op == OpRegisterInstance && late.iid != "" {
+				addLateInstance(late.sid, late.iid)
+			}
+		}()
+		return callResult{err: timeoutError(op, d)}
+	}
+}
+
+// lateInstances are instances registered after their registration timed out, they are not adopted by caller
+var lateInstances []staleInstance
+var lateMu sync.Mutex
+
+func addLateInstance(sid, iid string) {
+	lateMu.Lock()
+	defer lateMu.Unlock()
+	lateInstances = append(lateInstances, staleInstance{ServiceID: sid, InstanceID: iid})
+}
+
+// reconcileLateInstances unregisters the instances registered late except iid adopted by registration,
+// those failed to be unregistered are retried by the next registration
+func reconcileLateInstances(reg Registrator, iid string) {
+	lateMu.Lock()
+	late := lateInstances
+	lateInstances = nil
+	lateMu.Unlock()
+	var failed []staleInstance
+	for _, l := range late {
+		if l.InstanceID == iid {
+			continue
+		}
+		if err := reg.UnRegisterMicroServiceInstance(l.ServiceID, l.InstanceID); err != nil {
+			lager.Logger.Warnf("Unregister instance [%s/%s] registered after timeout failed: %s", l.ServiceID, l.InstanceID, err)
+			failed = append(failed, l)
+			continue
+		}
+		lager.Logger.Infof("Unregister instance [%s/%s] registered after timeout success", l.ServiceID, l.InstanceID)
+	}
+	if len(failed) > 0 {
+		lateMu.Lock()
+		lateInstances = append(lateInstances, failed...)
+		lateMu.Unlock()
+	}
+}
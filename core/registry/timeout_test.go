@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	initBootstrapEnv()
+	assert.Equal(t, time.Duration(0), operationTimeout(OpRegisterService))
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Timeout = "3s"
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations = model.RegistratorOperations{
+		RegisterService:  model.OperationStruct{Timeout: "1s"},
+		RegisterInstance: model.OperationStruct{Timeout: "2s"},
+	}
+	assert.Equal(t, 1*time.Second, operationTimeout(OpRegisterService))
+	assert.Equal(t, 2*time.Second, operationTimeout(OpRegisterInstance))
+	// falls back to overall timeout
+	assert.Equal(t, 3*time.Second, operationTimeout(OpAddSchemas))
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations.AddSchemas.Timeout = "invalid"
+	assert.Equal(t, time.Duration(0), operationTimeout(OpAddSchemas))
+}
+
+func TestCallWithTimeout(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations = model.RegistratorOperations{
+		RegisterService:  model.OperationStruct{Timeout: "10ms"},
+		RegisterInstance: model.OperationStruct{Timeout: "1s"},
+	}
+	r.delay[OpRegisterService] = 100 * time.Millisecond
+	r.delay[OpRegisterInstance] = 100 * time.Millisecond
+
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), OpRegisterService)
+
+	// register instance has a larger timeout than the delay
+	assert.NoError(t, RegisterMicroserviceInstances())
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations.RegisterInstance.Timeout = "10ms"
+	runtime.InstanceID = ""
+	err = RegisterMicroserviceInstances()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), OpRegisterInstance)
+	assert.Empty(t, runtime.InstanceID, "instance registered after timeout is not recorded")
+	// let the abandoned call finish before globals are reset by next test
+	time.Sleep(100 * time.Millisecond)
+
+	// the instance registered late is unregistered once registration succeeds with another instance
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations.RegisterInstance.Timeout = "1s"
+	r.mu.Lock()
+	r.iid = "iid2"
+	r.mu.Unlock()
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "iid2", runtime.InstanceID)
+	assert.Equal(t, []string{"sid/iid"}, r.unregistered)
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, 1, len(r.unregistered), "late instance is reconciled once")
+}
+
+func TestCallForIDsTimeout(t *testing.T) {
+	initBootstrapEnv()
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations = model.RegistratorOperations{
+		RegisterInstance: model.OperationStruct{Timeout: "10ms"},
+	}
+	finished := make(chan struct{})
+	sid, iid, err := callForIDs(OpRegisterInstance, func() (string, string, error) {
+		defer close(finished)
+		time.Sleep(50 * time.Millisecond)
+		return "sid", "iid", nil
+	})
+	assert.Error(t, err)
+	assert.Empty(t, sid, "late result is not handed to caller")
+	assert.Empty(t, iid)
+	<-finished
+	// the late instance is kept for reconcile when registration succeeds with the same instance
+	time.Sleep(10 * time.Millisecond)
+	lateMu.Lock()
+	assert.Equal(t, []staleInstance{{"sid", "iid"}}, lateInstances)
+	lateMu.Unlock()
+	reconcileLateInstances(DefaultRegistrator, "iid")
+	assert.Empty(t, lateInstances)
+
+	sid, iid, err = callForIDs(OpRegisterInstance, func() (string, string, error) {
+		return "sid", "iid", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "sid", sid)
+	assert.Equal(t, "iid", iid)
+}
+
+// contextRegistrator blocks registration until ctx is done
+type contextRegistrator struct {
+	*fakeRegistrator
+	keys []string
+}
+
+func (c *contextRegistrator) RegisterServiceWithContext(ctx context.Context, ms *MicroService) (string, error) {
+	c.keys = append(c.keys, IdempotencyKey(ctx))
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (c *contextRegistrator) RegisterServiceInstanceWithContext(ctx context.Context, sid string, ins *MicroServiceInstance) (string, error) {
+	c.keys = append(c.keys, IdempotencyKey(ctx))
+	return c.fakeRegistrator.RegisterServiceInstance(sid, ins)
+}
+
+func (c *contextRegistrator) AddSchemasWithContext(ctx context.Context, sid, schemaName, schemaInfo string) error {
+	return c.fakeRegistrator.AddSchemas(sid, schemaName, schemaInfo)
+}
+
+func TestCallWithContext(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	cr := &contextRegistrator{fakeRegistrator: r}
+	DefaultRegistrator = cr
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Operations = model.RegistratorOperations{
+		RegisterService: model.OperationStruct{Timeout: "10ms"},
+	}
+	err := RegisterMicroservice()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), OpRegisterService)
+	assert.Equal(t, 1, len(cr.keys), "returned once ctx is cancelled")
+	assert.NotEmpty(t, cr.keys[0], "key is carried by ctx")
+
+	runtime.ServiceID = "sid"
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, "iid", runtime.InstanceID)
+	assert.Equal(t, 1, len(r.instances))
+	assert.Equal(t, 2, len(cr.keys))
+}
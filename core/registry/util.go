@@ -10,13 +10,21 @@ import (
 	"fmt"
 	"github.com/cenkalti/backoff"
 	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
 	"github.com/go-chassis/go-chassis/core/config/model"
 	"github.com/go-chassis/go-chassis/core/lager"
 	chassisTLS "github.com/go-chassis/go-chassis/core/tls"
+	"github.com/go-chassis/go-chassis/pkg/util"
 	"github.com/go-chassis/go-chassis/pkg/util/iputil"
 )
 
-const protocolSymbol = "://"
+const (
+	protocolSymbol = "://"
+	// sslEndpointSuffix is appended to the protocol name of the TLS endpoint
+	sslEndpointSuffix = "-ssl"
+	sslEnabledTrue    = "?sslEnabled=true"
+	sslEnabledFalse   = "?sslEnabled=false"
+)
 
 //GetProtocolMap returns the protocol map
 func GetProtocolMap(eps []string) (map[string]string, string) {
@@ -40,6 +48,29 @@ func GetProtocolMap(eps []string) (map[string]string, string) {
 	return m, p
 }
 
+// GetProtocolMarks returns the marks of endpoints like sslEnabled and weight as raw query, keyed by protocol,
+// endpoints without marks are omitted
+func GetProtocolMarks(eps []string) map[string]string {
+	marks := make(map[string]string)
+	for _, ep := range eps {
+		u, err := url.Parse(ep)
+		if err != nil || u.Scheme == "" || u.RawQuery == "" {
+			continue
+		}
+		marks[u.Scheme] = u.RawQuery
+	}
+	return marks
+}
+
+// EndpointAddress returns the host:port of an endpoint without its scheme and marks like sslEnabled and weight
+func EndpointAddress(ep string) string {
+	addr := strings.SplitN(ep, "?", 2)[0]
+	if i := strings.Index(addr, protocolSymbol); i != -1 {
+		addr = addr[i+len(protocolSymbol):]
+	}
+	return addr
+}
+
 //GetProtocolList returns the protocol list
 func GetProtocolList(m map[string]string) []string {
 	eps := []string{}
@@ -68,34 +99,113 @@ func MakeEndpoints(m map[string]model.Protocol) []string {
 	return eps
 }
 
+// localIPFunc resolves the host to advertise when the configured one is unspecified
+var localIPFunc = iputil.GetLocalIP
+
+// modes of building the endpoint map
+const (
+	// EndpointMapStrict fails if any protocol endpoint can not be built, it is the default mode
+	EndpointMapStrict = "strict"
+	// EndpointMapLenient skips protocols whose endpoint can not be built with a warning,
+	// at least one endpoint must remain
+	EndpointMapLenient = "lenient"
+)
+
 //MakeEndpointMap returns the endpoints map
 func MakeEndpointMap(m map[string]model.Protocol) (map[string]string, error) {
+	lenient := config.GetRegistratorEndpointMap() == EndpointMapLenient
 	eps := make(map[string]string, 0)
-	for name, protocol := range m {
-		if len(protocol.Advertise) == 0 {
-			host, port, err := net.SplitHostPort(protocol.Listen)
-			if err != nil {
-				return nil, err
-			}
-			if host == "" || port == "" {
-				return nil, fmt.Errorf("listen address is invalid [%s]", protocol.Listen)
-			}
-			eps[name] = protocol.Listen
-		} else {
-			// check the provided Advertise ip is IPV4 or IPV6
-			host, port, err := net.SplitHostPort(protocol.Advertise)
-			if err != nil {
+	for _, name := range sortedProtocols(m) {
+		pEps, err := protocolEndpoints(name, m[name])
+		if err != nil {
+			if !lenient {
 				return nil, err
 			}
-			if host == "" || port == "" {
-				return nil, fmt.Errorf("advertise address is invalid [%s]", protocol.Advertise)
-			}
-			eps[name] = protocol.Advertise
+			lager.Logger.Warnf("Skip protocol [%s]: %s", name, err)
+			continue
+		}
+		for k, v := range pEps {
+			eps[k] = v
 		}
 	}
+	if lenient && len(eps) == 0 && len(m) != 0 {
+		return nil, fmt.Errorf("no endpoint can be built from %d protocols", len(m))
+	}
 	return eps, nil
 }
 
+// protocolEndpoints returns the endpoints of protocol name, with its ssl endpoint if it is advertised
+func protocolEndpoints(name string, protocol model.Protocol) (map[string]string, error) {
+	eps := make(map[string]string, 2)
+	if len(protocol.Advertise) == 0 {
+		ep, err := resolveEndpoint(protocol.Listen)
+		if err != nil {
+			return nil, fmt.Errorf("listen address of [%s] is invalid [%s]: %s", name, protocol.Listen, err)
+		}
+		eps[name] = ep
+	} else {
+		// check the provided Advertise ip is IPV4 or IPV6
+		ep, err := resolveEndpoint(protocol.Advertise)
+		if err != nil {
+			return nil, fmt.Errorf("advertise address of [%s] is invalid [%s]: %s", name, protocol.Advertise, err)
+		}
+		eps[name] = ep
+	}
+	if protocol.SSLAdvertise != "" {
+		key := name + sslEndpointSuffix
+		if _, _, err := util.ParsePortName(key); err != nil {
+			return nil, fmt.Errorf("can not advertise ssl endpoint of [%s]: %s", name, err)
+		}
+		ep, err := resolveEndpoint(protocol.SSLAdvertise)
+		if err != nil {
+			return nil, fmt.Errorf("ssl advertise address of [%s] is invalid [%s]: %s", name, protocol.SSLAdvertise, err)
+		}
+		// mark both endpoints so that consumers can tell which one is TLS
+		eps[key] = ep + sslEnabledTrue
+		eps[name] = eps[name] + sslEnabledFalse
+	}
+	if protocol.Weight != 0 {
+		if protocol.Weight < 0 {
+			return nil, fmt.Errorf("weight of [%s] must be positive, got %d", name, protocol.Weight)
+		}
+		for k, ep := range eps {
+			eps[k] = withEndpointWeight(ep, protocol.Weight)
+		}
+	}
+	return eps, nil
+}
+
+// resolveEndpoint makes sure the host of address is routable,
+// an empty, unspecified, loopback or link-local host is replaced by local ip, then by the fallback address,
+// loopback and link-local hosts are kept if neither resolves, they are still reachable nearby
+func resolveEndpoint(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if port == "" {
+		return "", fmt.Errorf("port is empty")
+	}
+	ip := net.ParseIP(host)
+	local := ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast())
+	if host != "" && !ip.IsUnspecified() && !local {
+		return addr, nil
+	}
+	if ip := localIPFunc(); ip != "" {
+		return net.JoinHostPort(ip, port), nil
+	}
+	fallback := config.GetRegistratorFallbackAddress()
+	if fallback == "" || net.ParseIP(fallback).IsUnspecified() {
+		if local {
+			lager.Logger.Warnf("can not resolve advertise host of [%s], it is only reachable nearby", addr)
+			return addr, nil
+		}
+		return "", fmt.Errorf("can not resolve advertise host, and no valid fallback address is configured")
+	}
+	lager.Logger.Warnf("can not resolve advertise host of [%s], use fallback address [%s]", addr, fallback)
+	return net.JoinHostPort(fallback, port), nil
+}
+
 //Microservice2ServiceKeyStr prepares a microservice key
 func Microservice2ServiceKeyStr(m *MicroService) string {
 	return strings.Join([]string{m.ServiceName, m.Version, m.AppID}, ":")
@@ -106,19 +216,33 @@ const (
 	maxInterval     = 3 * time.Minute
 )
 
-func startBackOff(operation func() error) {
-	backOff := &backoff.ExponentialBackOff{
-		InitialInterval:     initialInterval,
-		MaxInterval:         maxInterval,
-		RandomizationFactor: backoff.DefaultRandomizationFactor,
-		Multiplier:          backoff.DefaultMultiplier,
-		Clock:               backoff.SystemClock,
+// startBackOff retries operation until it succeeds,
+// it gives up once the shared retry budget of registration is exhausted,
+// or the retry classifier tells the error is not retryable
+func startBackOff(operation func() error) error {
+	backOff := &budgetBackOff{
+		BackOff: &backoff.ExponentialBackOff{
+			InitialInterval:     initialInterval,
+			MaxInterval:         maxInterval,
+			RandomizationFactor: backoff.DefaultRandomizationFactor,
+			Multiplier:          backoff.DefaultMultiplier,
+			Clock:               backoff.SystemClock,
+		},
+		budget: registrationBudget,
 	}
 	for {
 		lager.Logger.Infof("start backoff with initial interval %v", initialInterval)
-		err := backoff.Retry(operation, backOff)
+		err := backoff.Retry(classifiedOperation(operation), backOff)
 		if err == nil {
-			return
+			registrationBudget.reset()
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+		if registrationBudget.exhausted() {
+			lager.Logger.Errorf("retry budget of registration is exhausted, give up: %s", err)
+			return err
 		}
 	}
 }
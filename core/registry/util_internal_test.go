@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/pkg/util/iputil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEndpointFallback(t *testing.T) {
+	initBootstrapEnv()
+	defer func() { localIPFunc = iputil.GetLocalIP }()
+	protocols := map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "0.0.0.0:8080"},
+	}
+
+	localIPFunc = func() string { return "10.0.0.1" }
+	eps, err := MakeEndpointMap(protocols)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:8080", eps[common.ProtocolRest])
+
+	localIPFunc = func() string { return "" }
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress = "192.168.0.1"
+	eps, err = MakeEndpointMap(protocols)
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.1:8080", eps[common.ProtocolRest])
+
+	eps, err = MakeEndpointMap(map[string]model.Protocol{
+		common.ProtocolRest: {Advertise: ":8080"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.1:8080", eps[common.ProtocolRest])
+}
+
+func TestResolveEndpointBothFail(t *testing.T) {
+	initBootstrapEnv()
+	defer func() { localIPFunc = iputil.GetLocalIP }()
+	localIPFunc = func() string { return "" }
+
+	_, err := MakeEndpointMap(map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "0.0.0.0:8080"},
+	})
+	assert.Error(t, err)
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress = "0.0.0.0"
+	_, err = MakeEndpointMap(map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "0.0.0.0:8080"},
+	})
+	assert.Error(t, err)
+}
+
+func TestResolveEndpointLocalHost(t *testing.T) {
+	initBootstrapEnv()
+	defer func() { localIPFunc = iputil.GetLocalIP }()
+	for _, addr := range []string{"127.0.0.1:8080", "[::1]:8080", "169.254.0.1:8080", "[fe80::1]:8080"} {
+		localIPFunc = func() string { return "10.0.0.1" }
+		ep, err := resolveEndpoint(addr)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:8080", ep, addr)
+
+		localIPFunc = func() string { return "" }
+		config.GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress = "192.168.0.1"
+		ep, err = resolveEndpoint(addr)
+		assert.NoError(t, err)
+		assert.Equal(t, "192.168.0.1:8080", ep, addr)
+
+		config.GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress = ""
+		ep, err = resolveEndpoint(addr)
+		assert.NoError(t, err, "kept if neither resolves")
+		assert.Equal(t, addr, ep)
+	}
+}
+
+func TestMakeEndpointMapWithSSL(t *testing.T) {
+	initBootstrapEnv()
+	eps, err := MakeEndpointMap(map[string]model.Protocol{
+		common.ProtocolRest: {
+			Listen:       "127.0.0.1:8080",
+			SSLAdvertise: "127.0.0.1:8443",
+		},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(eps))
+	assert.Equal(t, "127.0.0.1:8080?sslEnabled=false", eps[common.ProtocolRest])
+	assert.Equal(t, "127.0.0.1:8443?sslEnabled=true", eps[common.ProtocolRest+"-ssl"])
+	assert.Equal(t, "127.0.0.1:9090", eps[common.ProtocolHighway])
+
+	_, err = MakeEndpointMap(map[string]model.Protocol{
+		"rest-admin": {Listen: "127.0.0.1:8080", SSLAdvertise: "127.0.0.1:8443"},
+	})
+	assert.Error(t, err)
+}
+
+func TestMakeEndpointMapLenient(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080"},
+		common.ProtocolHighway: {Listen: "127.0.0.1"},
+	}
+	_, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	assert.Error(t, err, "strict by default")
+	assert.Error(t, RegisterMicroservice())
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.EndpointMap = EndpointMapLenient
+	eps, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{common.ProtocolRest: "127.0.0.1:8080"}, eps)
+	assert.NoError(t, RegisterMicroservice())
+	assert.NoError(t, RegisterMicroserviceInstances())
+	assert.Equal(t, eps, r.instances[0].EndpointsMap)
+
+	// at least one endpoint must remain
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolHighway: {Listen: "127.0.0.1"},
+	}
+	_, err = MakeEndpointMap(config.GlobalDefinition.Cse.Protocols)
+	assert.Error(t, err)
+	err = ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cse.protocols")
+}
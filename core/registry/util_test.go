@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
 	"github.com/go-chassis/go-chassis/core/config/model"
 	"github.com/go-chassis/go-chassis/core/lager"
 	"github.com/go-chassis/go-chassis/core/registry"
@@ -12,6 +13,7 @@ import (
 )
 
 func TestMakeEndpointMap(t *testing.T) {
+	config.GlobalDefinition = &model.GlobalCfg{}
 	protocols := make(map[string]model.Protocol)
 	protocols[common.ProtocolRest] = model.Protocol{
 		Listen:    "0.0.0.0:1",
@@ -67,7 +69,9 @@ func TestUtil(t *testing.T) {
 	assert.Equal(t, common.ProtocolRest+":"+mapprotoRest[common.ProtocolRest].Advertise, common.ProtocolRest+":"+protocolArrRest[common.ProtocolRest])
 
 	// Advertise address are given in the protocol map for rest
-	// and addr is loopback ip. so it should return empty response
+	// and addr is loopback ip. so it should be replaced by local ip or fallback address
+	config.GlobalDefinition = &model.GlobalCfg{}
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.FallbackAddress = "10.0.0.1"
 	mapprotoRest[common.ProtocolRest] = model.Protocol{
 		Listen:    "0.0.0.2:1",
 		Advertise: "127.0.0.1:1",
@@ -76,10 +80,10 @@ func TestUtil(t *testing.T) {
 	protocolArrRest, _ = registry.MakeEndpointMap(mapprotoRest)
 	t.Log("making endpoints with listen and advertise addr, endpoint : ", protocolArrRest)
 	assert.NotNil(t, protocolArrRest)
-	assert.Equal(t, "127.0.0.1:1", protocolArrRest[common.ProtocolRest])
+	assert.NotEqual(t, "127.0.0.1:1", protocolArrRest[common.ProtocolRest])
 
 	// Advertise address are given in the protocol map for rest
-	// and addr is IPV6 ip. so it should return empty response
+	// and addr is IPV6 link-local ip. so it should be replaced by local ip or fallback address
 	mapprotoRest[common.ProtocolRest] = model.Protocol{
 		Listen:    "0.0.0.2:1",
 		Advertise: "[fe80::3436:b05c:350a:1ccd]:1",
@@ -88,7 +92,7 @@ func TestUtil(t *testing.T) {
 	protocolArrRest, _ = registry.MakeEndpointMap(mapprotoRest)
 	t.Log("making endpoints with listen and advertise addr, endpoint : ", protocolArrRest)
 	assert.NotNil(t, protocolArrRest)
-	assert.Equal(t, "[fe80::3436:b05c:350a:1ccd]:1", protocolArrRest[common.ProtocolRest])
+	assert.NotEqual(t, "[fe80::3436:b05c:350a:1ccd]:1", protocolArrRest[common.ProtocolRest])
 
 	// Advertise address is not given so based on the listen address it should choose the advertise addr.
 	mapprotoRest[common.ProtocolRest] = model.Protocol{
@@ -141,3 +145,11 @@ func TestURIs2Hosts(t *testing.T) {
 	assert.Equal(t, "http", s)
 	assert.Equal(t, "127.0.0.1:8080", hosts[0])
 }
+
+func TestGetProtocolMapQuery(t *testing.T) {
+	mp, _ := registry.GetProtocolMap([]string{"rest://10.0.0.1:8443?sslEnabled=true", "highway://10.0.0.1:9090"})
+	assert.Equal(t, "10.0.0.1:8443", mp["rest"], "marks are stripped")
+	assert.Equal(t, "10.0.0.1:9090", mp["highway"])
+	assert.Equal(t, map[string]string{"rest": "sslEnabled=true"}, registry.GetProtocolMarks([]string{"rest://10.0.0.1:8443?sslEnabled=true", "highway://10.0.0.1:9090"}))
+	assert.Equal(t, "10.0.0.1:8443", registry.EndpointAddress("https://10.0.0.1:8443?weight=2"))
+}
@@ -0,0 +1,402 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/go-chassis/go-chassis/core/lager"
+	"github.com/go-chassis/go-chassis/pkg/util"
+)
+
+// limits checked before registration
+const (
+	maxNameLength  = 128
+	maxAliasLength = 256
+	// maxMetadataSize is the max total length of keys and values of a metadata set
+	maxMetadataSize = 5 * 1024
+	// sunsetDateLayout is the format of the date a deprecated service is sunset
+	sunsetDateLayout = "2006-01-02"
+)
+
+var (
+	nameRegex    = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_\-.]*[a-zA-Z0-9])?$`)
+	versionRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,3}$`)
+	aliasRegex   = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_\-.:]*[a-zA-Z0-9])?$`)
+	levels       = map[string]bool{"": true, "FRONT": true, "MIDDLE": true, "BACK": true}
+	// authSchemes are the authentication schemes a service can advertise, in lower case
+	authSchemes = map[string]bool{"jwt": true, "mtls": true, "apikey": true, "basic": true, "oauth2": true}
+)
+
+// FieldError is a problem found in a config field, Field is the path of it
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every field problem found by ValidateRegistrationConfig
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	problems := make([]string, 0, len(e))
+	for _, fe := range e {
+		problems = append(problems, fe.Error())
+	}
+	return "invalid registration config: " + strings.Join(problems, "; ")
+}
+
+// ValidateRegistrationConfig validates the config of self micro-service and instance at once,
+// it returns a ValidationError listing every problem found with its field path,
+// the format of names and versions, protocol names and the size of properties are only checked by chassis,
+// they are logged as warnings unless registrator.strict is enabled
+func ValidateRegistrationConfig() error {
+	var problems, warnings ValidationError
+	add := func(field, format string, args ...interface{}) {
+		problems = append(problems, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := add
+	if !config.GetRegistratorStrict() {
+		warn = func(field, format string, args ...interface{}) {
+			warnings = append(warnings, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+	desc := config.MicroserviceDefinition.ServiceDescription
+
+	if desc.Name == "" {
+		add("service_description.name", "service name is empty")
+	} else if len(desc.Name) > maxNameLength || !nameRegex.MatchString(desc.Name) {
+		warn("service_description.name", "service name [%s] is invalid", desc.Name)
+	}
+	if desc.Version == "" {
+		add("service_description.version", "service version is empty")
+	} else if !versionRegex.MatchString(desc.Version) {
+		warn("service_description.version", "service version [%s] is invalid", desc.Version)
+	}
+	if desc.MinClientVersion != "" && !versionRegex.MatchString(desc.MinClientVersion) {
+		add("service_description.minClientVersion", "min client version [%s] is invalid", desc.MinClientVersion)
+	}
+	if desc.DisplayName != "" && strings.TrimSpace(desc.DisplayName) == "" {
+		add("service_description.displayName", "display name is blank")
+	} else if len(desc.DisplayName) > maxAliasLength {
+		add("service_description.displayName", "display name is longer than %d", maxAliasLength)
+	}
+	for i, c := range desc.Categories {
+		field := fmt.Sprintf("service_description.categories[%d]", i)
+		if strings.TrimSpace(c) == "" {
+			add(field, "category must not be empty")
+		} else if strings.Contains(c, ",") {
+			add(field, "category [%s] must not contain comma", c)
+		}
+	}
+	if desc.LBStrategy != "" && !knownLBStrategy(desc.LBStrategy) {
+		add("service_description.lbStrategy", "load balance strategy [%s] is unknown", desc.LBStrategy)
+	}
+	for i, p := range desc.ServicePaths {
+		if strings.TrimSpace(p.Path) == "" {
+			add(fmt.Sprintf("service_description.paths[%d].path", i), "service path must not be empty")
+		}
+	}
+	seenDeps := make(map[string]bool, len(desc.Dependencies))
+	for i, d := range desc.Dependencies {
+		field := fmt.Sprintf("service_description.dependencies[%d]", i)
+		if len(d.Name) > maxNameLength || !nameRegex.MatchString(d.Name) {
+			add(field+".name", "provider name [%s] is invalid", d.Name)
+		} else if seenDeps[d.Name] {
+			add(field+".name", "duplicated provider [%s]", d.Name)
+		}
+		seenDeps[d.Name] = true
+		if d.AppID != "" && (len(d.AppID) > maxNameLength || !nameRegex.MatchString(d.AppID)) {
+			add(field+".appId", "provider app [%s] is invalid", d.AppID)
+		}
+		if d.Version != "" {
+			if err := validVersionRule(d.Version); err != nil {
+				add(field+".version", "%s", err)
+			}
+		}
+	}
+	refVersions := make(map[string]string)
+	for name, ref := range config.GetRouterReference() {
+		refVersions[name] = ref.Version
+	}
+	for _, name := range sortedKeys(refVersions) {
+		if v := refVersions[name]; v != "" {
+			if err := validVersionRule(v); err != nil {
+				add("cse.references."+name+".version", "%s", err)
+			}
+		}
+	}
+	seenSchemes := make(map[string]bool, len(desc.AuthSchemes))
+	for i, s := range desc.AuthSchemes {
+		field := fmt.Sprintf("service_description.authSchemes[%d]", i)
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !authSchemes[s] {
+			add(field, "auth scheme [%s] is unknown", s)
+		} else if seenSchemes[s] {
+			add(field, "duplicated auth scheme [%s]", s)
+		}
+		seenSchemes[s] = true
+	}
+	seenEnvs := make(map[string]bool, len(desc.Environments))
+	for i, e := range desc.Environments {
+		field := fmt.Sprintf("service_description.environments[%d]", i)
+		if len(e) > maxNameLength || !nameRegex.MatchString(e) {
+			add(field, "environment [%s] is invalid", e)
+		} else if seenEnvs[e] {
+			add(field, "duplicated environment [%s]", e)
+		}
+		seenEnvs[e] = true
+	}
+	if config.GetRegistratorStrict() {
+		if strings.TrimSpace(desc.Owner) == "" {
+			add("service_description.owner", "owner is required in strict mode")
+		}
+		if strings.TrimSpace(desc.Contact) == "" {
+			add("service_description.contact", "contact is required in strict mode")
+		}
+	}
+	if desc.Tier != "" && !containsString(config.GetRegistratorTiers(), desc.Tier) {
+		add("service_description.tier", "service tier [%s] is not one of %v", desc.Tier, config.GetRegistratorTiers())
+	}
+	if desc.DocsURL != "" {
+		if u, err := url.Parse(desc.DocsURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("service_description.docsURL", "docs url [%s] must be an absolute http or https url", desc.DocsURL)
+		}
+	}
+	if !desc.Deprecated && (desc.DeprecationMessage != "" || desc.SunsetDate != "") {
+		add("service_description.deprecated", "deprecation message and sunset date are only allowed for deprecated service")
+	}
+	if desc.SunsetDate != "" {
+		if _, err := time.Parse(sunsetDateLayout, desc.SunsetDate); err != nil {
+			add("service_description.sunsetDate", "sunset date [%s] must be in format %s", desc.SunsetDate, sunsetDateLayout)
+		}
+	}
+	if len(desc.DeprecationMessage) > maxAliasLength {
+		add("service_description.deprecationMessage", "deprecation message is longer than %d", maxAliasLength)
+	}
+	if desc.MaxConcurrency < 0 {
+		add("service_description.maxConcurrency", "max concurrency must be a positive integer, got %d", desc.MaxConcurrency)
+	}
+	if desc.RateLimitHint < 0 {
+		add("service_description.rateLimitHint", "rate limit hint must be a positive requests per second, got %d", desc.RateLimitHint)
+	}
+	if !levels[desc.Level] {
+		add("service_description.level", "service level [%s] is invalid, must be FRONT, MIDDLE or BACK", desc.Level)
+	}
+	if app := config.GetRegistratorAppID(); app != "" && (len(app) > maxNameLength || !nameRegex.MatchString(app)) {
+		add("cse.service.registry.registrator.appId", "app id override [%s] is invalid", app)
+	}
+	if k := desc.ServiceKey; k.App != "" && (len(k.App) > maxNameLength || !nameRegex.MatchString(k.App)) {
+		add("service_description.serviceKey.app", "app override [%s] is invalid", k.App)
+	}
+	if k := desc.ServiceKey; k.Name != "" && (len(k.Name) > maxNameLength || !nameRegex.MatchString(k.Name)) {
+		add("service_description.serviceKey.name", "service name override [%s] is invalid", k.Name)
+	}
+	if k := desc.ServiceKey; k.Version != "" && !versionRegex.MatchString(k.Version) {
+		add("service_description.serviceKey.version", "service version override [%s] is invalid", k.Version)
+	}
+	if k := desc.ServiceKey; k.Environment != "" && (len(k.Environment) > maxNameLength || !nameRegex.MatchString(k.Environment)) {
+		add("service_description.serviceKey.environment", "environment override [%s] is invalid", k.Environment)
+	}
+	key := registrationKey()
+	if key.App == "" {
+		add("APPLICATION_ID", "app is empty")
+	} else if alias := key.App + ":" + key.Name; len(alias) > maxAliasLength || !aliasRegex.MatchString(alias) {
+		warn("service_description.name", "alias [%s] is invalid", alias)
+	}
+	if config.GlobalDefinition.DataCenter != nil {
+		if err := validAvailableZone(config.GlobalDefinition.DataCenter.AvailableZone); err != nil {
+			add("region.availableZone", "%s", err)
+		}
+	}
+
+	switch config.GetRegistratorRegistrationOrder() {
+	case "", RegistrationOrderServiceFirst, RegistrationOrderInstanceFirst:
+	default:
+		add("cse.service.registry.registrator.registrationOrder", "registration order [%s] is unknown, must be %s or %s",
+			config.GetRegistratorRegistrationOrder(), RegistrationOrderServiceFirst, RegistrationOrderInstanceFirst)
+	}
+	if _, err := metadataEncoder(); err != nil {
+		add("cse.service.registry.registrator.metadataEncoding", "%s", err)
+	}
+	if config.GetRegistratorRequireSignature() && getPayloadSigner() == nil {
+		add("cse.service.registry.registrator.requireSignature", "registry requires signed payload, but no payload signer is set")
+	}
+
+	if config.GetRegistratorPublishRouteRules() {
+		problems = append(problems, routeRuleProblems(desc.RouteRules)...)
+	}
+
+	// in lenient endpoint map mode broken endpoints are skipped, as long as one remains
+	lenient := config.GetRegistratorEndpointMap() == EndpointMapLenient
+	for _, name := range sortedProtocols(config.GlobalDefinition.Cse.Protocols) {
+		p := config.GlobalDefinition.Cse.Protocols[name]
+		field := "cse.protocols." + name
+		if _, _, err := util.ParsePortName(name); err != nil {
+			warn(field, "protocol name [%s] is invalid: %s", name, err)
+		}
+		if !lenient {
+			addressProblems(add, warn, field, name, p)
+		}
+		if p.BasePath != "" {
+			if err := validBasePath(p.BasePath); err != nil {
+				add(field+".basePath", "%s", err)
+			}
+		}
+		if p.Weight < 0 {
+			add(field+".weight", "weight must be positive, got %d", p.Weight)
+		}
+		if p.HealthPath != "" {
+			if err := validHealthPath(p.HealthPath); err != nil {
+				add(field+".healthPath", "%s", err)
+			}
+		}
+	}
+	advertised := instanceEndpoints()
+	if lenient && advertised == nil {
+		if _, err := MakeEndpointMap(config.GlobalDefinition.Cse.Protocols); err != nil {
+			add("cse.protocols", "%s", err)
+		}
+	}
+	for _, name := range sortedKeys(advertised) {
+		if err := validEndpoint(advertised[name]); err != nil {
+			add("InstanceEndpoints."+name, "%s", err)
+		}
+	}
+
+	if n := metadataSize(desc.Properties); n > maxMetadataSize {
+		warn("service_description.properties", "service properties size %d exceeds %d", n, maxMetadataSize)
+	}
+	if n := metadataSize(desc.InstanceProperties); n > maxMetadataSize {
+		warn("service_description.instance_properties", "instance properties size %d exceeds %d", n, maxMetadataSize)
+	}
+
+	for _, w := range warnings {
+		lager.Logger.Warnf("registration config %s, it is rejected in strict mode", w)
+	}
+
+	if len(problems) != 0 {
+		return problems
+	}
+	return nil
+}
+
+func sortedProtocols(m map[string]model.Protocol) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validEndpoint checks the address and the weight of an endpoint
+func validEndpoint(ep string) error {
+	if _, _, err := net.SplitHostPort(EndpointAddress(ep)); err != nil {
+		return fmt.Errorf("instance endpoint [%s] is invalid: %s", ep, err)
+	}
+	if _, err := EndpointWeight(ep); err != nil {
+		return fmt.Errorf("instance endpoint [%s] is invalid: %s", ep, err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addressProblems adds problems of the listen, advertise and ssl advertise addresses of protocol name,
+// a protocol name which can not carry the ssl suffix is only warned
+func addressProblems(add, warn func(field, format string, args ...interface{}), field, name string, p model.Protocol) {
+	if p.Advertise == "" {
+		if _, err := resolveEndpoint(p.Listen); err != nil {
+			add(field+".listenAddress", "listen address [%s] is invalid: %s", p.Listen, err)
+		}
+	} else if _, err := resolveEndpoint(p.Advertise); err != nil {
+		add(field+".advertiseAddress", "advertise address [%s] is invalid: %s", p.Advertise, err)
+	}
+	if p.SSLAdvertise != "" {
+		if _, _, err := util.ParsePortName(name + sslEndpointSuffix); err != nil {
+			warn(field+".sslAdvertiseAddress", "can not advertise ssl endpoint: %s", err)
+		} else if _, err := resolveEndpoint(p.SSLAdvertise); err != nil {
+			add(field+".sslAdvertiseAddress", "ssl advertise address [%s] is invalid: %s", p.SSLAdvertise, err)
+		}
+	}
+}
+
+// validAvailableZone checks the zone is one of registrator.availableZones, it only takes effect when they are configured
+func validAvailableZone(zone string) error {
+	zones := config.GetRegistratorAvailableZones()
+	if zone == "" || len(zones) == 0 || containsString(zones, zone) {
+		return nil
+	}
+	return fmt.Errorf("available zone [%s] is not one of %v", zone, zones)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataSize(md map[string]string) int {
+	n := 0
+	for k, v := range md {
+		n += len(k) + len(v)
+	}
+	return n
+}
+
+// knownLBStrategy tells whether name is a built in load balance strategy, or one the checker knows,
+// any strategy is accepted if no checker is set
+func knownLBStrategy(name string) bool {
+	switch name {
+	case common.StrategyRoundRobin, common.StrategyRandom, common.StrategySessionStickiness, common.StrategyLatency:
+		return true
+	}
+	check := getLBStrategyChecker()
+	if check == nil {
+		lager.Logger.Warnf("No load balance strategy checker is set, strategy [%s] is not checked", name)
+		return true
+	}
+	return check(name)
+}
+
+// LBStrategyChecker tells whether a load balance strategy is installed
+type LBStrategyChecker func(name string) bool
+
+var lbStrategyChecker LBStrategyChecker
+var lbStrategyCheckerMu sync.RWMutex
+
+// SetLBStrategyChecker sets the checker of service_description.lbStrategy which is not built in,
+// chassis sets it with the installed strategies of load balancer before registry is enabled
+func SetLBStrategyChecker(c LBStrategyChecker) {
+	lbStrategyCheckerMu.Lock()
+	lbStrategyChecker = c
+	lbStrategyCheckerMu.Unlock()
+}
+
+func getLBStrategyChecker() LBStrategyChecker {
+	lbStrategyCheckerMu.RLock()
+	defer lbStrategyCheckerMu.RUnlock()
+	return lbStrategyChecker
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-chassis/go-chassis/core/common"
+	"github.com/go-chassis/go-chassis/core/config"
+	"github.com/go-chassis/go-chassis/core/config/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRegistrationConfig(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	assert.NoError(t, ValidateRegistrationConfig())
+
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Name = "-bad name"
+	desc.Version = "v1"
+	desc.Level = "TOP"
+	desc.InstanceProperties = map[string]string{"k": strings.Repeat("v", maxMetadataSize)}
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest: {Listen: "127.0.0.1"},
+		"a-b-c":             {Listen: "127.0.0.1:8080"},
+	}
+	InstanceEndpoints = map[string]string{"rest": "no-port"}
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Strict = true
+	desc.Owner, desc.Contact = "team", "team@example.com"
+	err := ValidateRegistrationConfig()
+	assert.Error(t, err)
+	for _, s := range []string{"service name", "service version", "service level", "alias",
+		"cse.protocols.rest.listenAddress", "protocol name [a-b-c]", "instance endpoint", "instance properties size"} {
+		assert.Contains(t, err.Error(), s)
+	}
+
+	assert.Error(t, RegisterMicroservice())
+	assert.Empty(t, r.services, "nothing is registered with invalid config")
+
+	// only warned unless strict
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Strict = false
+	err = ValidateRegistrationConfig()
+	assert.Error(t, err)
+	for _, s := range []string{"service name", "service version", "alias", "protocol name", "instance properties size"} {
+		assert.NotContains(t, err.Error(), s)
+	}
+	assert.Contains(t, err.Error(), "service level")
+	assert.Contains(t, err.Error(), "cse.protocols.rest.listenAddress")
+}
+
+func TestValidateRegistrationConfigLenient(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Name = "bad name"
+	desc.Version = "v1"
+	desc.Properties = map[string]string{"k": strings.Repeat("v", maxMetadataSize)}
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		"a-b-c": {Listen: "127.0.0.1:8080"},
+	}
+	assert.NoError(t, ValidateRegistrationConfig())
+	assert.NoError(t, RegisterMicroservice())
+	assert.Equal(t, 1, len(r.services), "config working before validation is still registered")
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.Strict = true
+	desc.Owner, desc.Contact = "team", "team@example.com"
+	assert.Error(t, ValidateRegistrationConfig())
+}
+
+func TestValidationFieldPaths(t *testing.T) {
+	initBootstrapEnv()
+	desc := &config.MicroserviceDefinition.ServiceDescription
+	desc.Version = ""
+	desc.Categories = []string{"payment", " "}
+	config.GlobalDefinition.Cse.Protocols = map[string]model.Protocol{
+		common.ProtocolRest:    {Listen: "127.0.0.1:8080", Advertise: "127.0.0.1"},
+		common.ProtocolHighway: {Listen: "127.0.0.1:9090", SSLAdvertise: "127.0.0.1"},
+	}
+	InstanceEndpoints = map[string]string{"rest": "no-port"}
+
+	err := ValidateRegistrationConfig()
+	verr, ok := err.(ValidationError)
+	assert.True(t, ok)
+	var fields []string
+	for _, fe := range verr {
+		fields = append(fields, fe.Field)
+	}
+	assert.Equal(t, []string{
+		"service_description.version",
+		"service_description.categories[1]",
+		"cse.protocols.highway.sslAdvertiseAddress",
+		"cse.protocols.rest.advertiseAddress",
+		"InstanceEndpoints.rest",
+	}, fields)
+	assert.Contains(t, err.Error(), "service_description.version: service version is empty")
+}
+
+func TestValidateAvailableZone(t *testing.T) {
+	r, _ := initBootstrapEnv()
+	config.GlobalDefinition.DataCenter = &model.DataCenterInfo{Name: "dc", AvailableZone: "az-2"}
+	// disabled by default
+	assert.NoError(t, ValidateRegistrationConfig())
+
+	config.GlobalDefinition.Cse.Service.Registry.Registrator.AvailableZones = []string{"az-1", "az-2"}
+	assert.NoError(t, ValidateRegistrationConfig())
+
+	config.GlobalDefinition.DataCenter.AvailableZone = "az2"
+	err := ValidateRegistrationConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "region.availableZone")
+	assert.Error(t, RegisterMicroserviceInstances())
+	assert.Empty(t, r.instances)
+}
@@ -62,6 +62,7 @@ func StartServer() error {
 			lager.Logger.Errorf("servers failed to start, err %s", err)
 			return fmt.Errorf("can not start [%s] server,%s", name, err.Error())
 		}
+		registry.SetServerRunning(name, true)
 		lager.Logger.Info(name + " server start success")
 	}
 	lager.Logger.Info("All server Start Completed")
@@ -3,6 +3,7 @@ package eventlistener
 import (
 	"github.com/go-chassis/go-archaius"
 	"github.com/go-chassis/go-archaius/core"
+	"github.com/go-chassis/go-chassis/core/registry"
 )
 
 //RegisterKeys registers a config key to the archaius
@@ -21,5 +22,8 @@ func Init() {
 	RegisterKeys(circuitBreakerEventListener, ConsumerFallbackKey, ConsumerFallbackPolicyKey, ConsumerIsolationKey, ConsumerCircuitbreakerKey)
 	RegisterKeys(lbEventListener, LoadBalanceKey)
 	RegisterKeys(&DarkLaunchEventListener{}, DarkLaunchKey)
+	if registry.RegistrationActive() {
+		RegisterKeys(&registry.InstancePropertiesListener{}, registry.InstancePropertiesKey)
+	}
 
 }
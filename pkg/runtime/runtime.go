@@ -2,8 +2,9 @@ package runtime
 
 //Status
 const (
-	StatusRunning = "UP"
-	StatusDown    = "DOWN"
+	StatusRunning      = "UP"
+	StatusDown         = "DOWN"
+	StatusOutOfService = "OUTOFSERVICE"
 )
 
 //HostName is the host name of service host